@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"nerdbucket.com/go/noid/noid"
+	"os"
+	"strconv"
+)
+
+func blockUsageError(message string) {
+	fmt.Println(message)
+	fmt.Println("")
+	blockUsage()
+	os.Exit(1)
+}
+
+func blockUsage() {
+	fmt.Println("Usage: noid-cli block add SUBSTRING [SUBSTRING ...]")
+	fmt.Println("       noid-cli block add-pattern REGEX [REGEX ...]")
+	fmt.Println("       noid-cli block remove SUBSTRING|REGEX")
+	fmt.Println("       noid-cli block list")
+	fmt.Println("       noid-cli block impact [MAX_CHECKS]")
+	fmt.Println("")
+}
+
+func cmdBlockHelp() {
+	blockUsage()
+	fmt.Println("Manages the blocklist of the noid database in the current working directory.")
+	fmt.Println("The minter skips any noid containing a blocked substring (ignoring case) or")
+	fmt.Println("matching a blocked regular expression.  Only the part of a noid after its")
+	fmt.Println(`prefix is checked.  "impact" reports how many noids the blocklist removes`)
+	fmt.Println("from the template, checking every noid if there are no more than MAX_CHECKS")
+	fmt.Println("(default 1000000) and estimating from an even sample otherwise, e.g.:")
+	fmt.Println("")
+	fmt.Println("    noid-cli block add test tmp")
+	fmt.Println(`    noid-cli block add-pattern "^[0-9]+$"`)
+	fmt.Println("    noid-cli block impact")
+	os.Exit(1)
+}
+
+func cmdBlock(args []string) {
+	if len(args) < 1 {
+		blockUsageError("Block command requires a sub-command")
+	}
+
+	var fn func(*noid.Minter) error
+	switch args[0] {
+	case "add":
+		if len(args) < 2 {
+			blockUsageError(`"block add" requires at least one substring`)
+		}
+		fn = func(m *noid.Minter) error { return m.Block(args[1:]...) }
+
+	case "add-pattern":
+		if len(args) < 2 {
+			blockUsageError(`"block add-pattern" requires at least one pattern`)
+		}
+		fn = func(m *noid.Minter) error { return m.BlockPattern(args[1:]...) }
+
+	case "remove":
+		if len(args) != 2 {
+			blockUsageError(`"block remove" takes 1 argument`)
+		}
+		fn = func(m *noid.Minter) error { return m.Unblock(args[1]) }
+
+	case "list":
+		if len(args) != 1 {
+			blockUsageError(`"block list" takes no arguments`)
+		}
+		cmdBlockList()
+		return
+
+	case "impact":
+		if len(args) > 2 {
+			blockUsageError(`"block impact" takes at most 1 argument`)
+		}
+		cmdBlockImpact(args[1:])
+		return
+
+	default:
+		blockUsageError(fmt.Sprintf(`"block %s" is not a valid command`, args[0]))
+	}
+
+	err := openStore("noid.db").Update(fn)
+	if err != nil {
+		blockUsageError(fmt.Sprintf("Unable to update blocklist: %s", err))
+	}
+}
+
+func loadBlockMinter() *noid.Minter {
+	m, err := openStore("noid.db").Load()
+	if err != nil {
+		blockUsageError(fmt.Sprintf("Error building minter from noid.db: %s", err))
+	}
+	return m
+}
+
+func cmdBlockList() {
+	b := loadBlockMinter().Blocklist()
+	for _, s := range b.Substrings {
+		fmt.Println(s)
+	}
+	for _, p := range b.Patterns {
+		fmt.Printf("/%s/\n", p)
+	}
+}
+
+func cmdBlockImpact(args []string) {
+	var maxChecks uint64 = 1000000
+	if len(args) == 1 {
+		var err error
+		maxChecks, err = strconv.ParseUint(args[0], 10, 64)
+		if err != nil || maxChecks == 0 {
+			blockUsageError(fmt.Sprintf(`Invalid check count "%s"`, args[0]))
+		}
+	}
+
+	impact := loadBlockMinter().BlocklistImpact(maxChecks)
+	if impact.Exhaustive {
+		fmt.Printf("%d of %d noids are blocked\n", impact.Blocked, impact.Total)
+		return
+	}
+
+	fmt.Printf("%d of %d sampled noids are blocked\n", impact.Blocked, impact.Checked)
+	fmt.Printf("About %d of %d noids are blocked\n", impact.Estimate(), impact.Total)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"nerdbucket.com/go/noid/noid"
+	"os"
+	"time"
+)
+
+func dbUsageError(message string) {
+	fmt.Println(message)
+	fmt.Println("")
+	dbUsage()
+	os.Exit(1)
+}
+
+func dbUsage() {
+	fmt.Println("Usage: noid-cli db verify [FILE]")
+	fmt.Println("       noid-cli db migrate [FILE]")
+	fmt.Println("")
+}
+
+func cmdDbHelp() {
+	dbUsage()
+	fmt.Println("Checks and upgrades noid database files.  FILE defaults to noid.db in the")
+	fmt.Println("current working directory.")
+	fmt.Println("")
+	fmt.Println(`"verify" reads the file without changing it, checking its format version,`)
+	fmt.Println("its checksum, and the minter state inside it, and prints the file's details.")
+	fmt.Println("Any problem is printed and the command exits with a non-zero status.")
+	fmt.Println("")
+	fmt.Println(`"migrate" converts a database written before database files were versioned`)
+	fmt.Println("to the current format.  Other commands refuse to use an unversioned file")
+	fmt.Println("until it's migrated.  Files already in the current format are left alone,")
+	fmt.Println("e.g.:")
+	fmt.Println("")
+	fmt.Println("    noid-cli db migrate")
+	fmt.Println("    noid-cli db verify")
+	os.Exit(1)
+}
+
+func cmdDb(args []string) {
+	if len(args) < 1 {
+		dbUsageError("Db command requires a sub-command")
+	}
+
+	filename := "noid.db"
+	if len(args) == 2 {
+		filename = args[1]
+	}
+	if len(args) > 2 {
+		dbUsageError(fmt.Sprintf(`"db %s" takes at most 1 argument`, args[0]))
+	}
+
+	switch args[0] {
+	case "verify":
+		cmdDbVerify(filename)
+
+	case "migrate":
+		cmdDbMigrate(filename)
+
+	default:
+		dbUsageError(fmt.Sprintf(`"db %s" is not a valid command`, args[0]))
+	}
+}
+
+func cmdDbVerify(filename string) {
+	sf, err := openStore(filename).Verify()
+	if err != nil {
+		fmt.Printf("%s is NOT valid: %s\n", filename, err)
+		if errors.Is(err, noid.ErrUnversionedStore) {
+			fmt.Printf(`Run "noid-cli db migrate %s" to convert it`+"\n", filename)
+		}
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s is valid\n", filename)
+	fmt.Printf("Format:   %d\n", sf.Format)
+	fmt.Printf("Created:  %s\n", sf.Created.Format(time.RFC3339))
+	fmt.Printf("Modified: %s\n", sf.Modified.Format(time.RFC3339))
+	fmt.Printf("Checksum: %s\n", sf.Checksum)
+}
+
+func cmdDbMigrate(filename string) {
+	if err := openStore(filename).Migrate(); err != nil {
+		dbUsageError(fmt.Sprintf("Unable to migrate %s: %s", filename, err))
+	}
+	fmt.Printf("%s is in format %d\n", filename, noid.StoreFormat)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"nerdbucket.com/go/noid/noid"
+	"os"
+)
+
+func extractUsageError(message string) {
+	fmt.Println(message)
+	fmt.Println("")
+	extractUsage()
+	os.Exit(1)
+}
+
+func extractUsage() {
+	fmt.Println("Usage: noid-cli extract TEMPLATE [options] < file")
+	fmt.Println("")
+}
+
+func cmdExtractHelp() {
+	extractUsage()
+	fmt.Println("Finds every noid in standard input which could have come from TEMPLATE,")
+	fmt.Println("such as in OCR text, finding aids, or log files.  Check digits are verified,")
+	fmt.Println("so most look-alikes are skipped.  Each noid is printed with its byte offset")
+	fmt.Println("and whether the noid database in the current working directory has minted")
+	fmt.Println("it, e.g.:")
+	fmt.Println("")
+	fmt.Println("    1043: ph.q67j4g (minted)")
+	fmt.Println("    2210: ph.x78c1m (not minted)")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("")
+	fmt.Println(templateOptionsHelp)
+	os.Exit(1)
+}
+
+func cmdExtract(args []string) {
+	t := templateFromArgs(args, extractUsageError)
+	m, err := openStore("noid.db").Load()
+	if err != nil {
+		extractUsageError(fmt.Sprintf("Error building minter from noid.db: %s", err))
+	}
+
+	s := noid.NewScanner(t, os.Stdin)
+	for s.Scan() {
+		o := s.Occurrence()
+		minted, err := m.Minted(o.Canonical)
+		switch {
+		case err != nil:
+			fmt.Printf("%d: %s (not from noid.db's template)\n", o.Offset, o.Text)
+		case minted:
+			fmt.Printf("%d: %s (minted)\n", o.Offset, o.Text)
+		default:
+			fmt.Printf("%d: %s (not minted)\n", o.Offset, o.Text)
+		}
+	}
+	if err := s.Err(); err != nil {
+		extractUsageError(fmt.Sprintf("Error reading input: %s", err))
+	}
+}
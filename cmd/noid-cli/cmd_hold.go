@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"nerdbucket.com/go/noid/noid"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func holdUsageError(message string) {
+	fmt.Println(message)
+	fmt.Println("")
+	holdUsage()
+	os.Exit(1)
+}
+
+func holdUsage() {
+	fmt.Println("Usage: noid-cli hold add [NOID ...]")
+	fmt.Println("       noid-cli hold remove [NOID ...]")
+	fmt.Println("       noid-cli hold add-range START END")
+	fmt.Println("       noid-cli hold remove-range START END")
+	fmt.Println("       noid-cli hold list")
+	fmt.Println("")
+}
+
+func cmdHoldHelp() {
+	holdUsage()
+	fmt.Println("Manages the list of noids the noid database in the current working directory")
+	fmt.Println(`must never mint.  "add" and "remove" take noids on the command line, or read`)
+	fmt.Println(`them from standard input, one per line, if none are given.  The "-range"`)
+	fmt.Println("variants work on inclusive ranges of sequence values instead, e.g.:")
+	fmt.Println("")
+	fmt.Println("    noid-cli hold add q67j4g y67j4r")
+	fmt.Println("    noid-cli hold add < legacy-noids.txt")
+	fmt.Println("    noid-cli hold add-range 1000 1999")
+	fmt.Println("    noid-cli hold list")
+	fmt.Println("")
+	fmt.Println("Databases whose template has date placeholders can't hold noids.")
+	os.Exit(1)
+}
+
+func cmdHold(args []string) {
+	if len(args) < 1 {
+		holdUsageError("Hold command requires a sub-command")
+	}
+
+	var fn func(*noid.Minter) error
+	switch args[0] {
+	case "add":
+		ids := noidsFromArgsOrStdin(args[1:])
+		fn = func(m *noid.Minter) error { return m.Hold(ids...) }
+
+	case "remove":
+		ids := noidsFromArgsOrStdin(args[1:])
+		fn = func(m *noid.Minter) error { return m.Release(ids...) }
+
+	case "add-range":
+		start, end := rangeFromArgs(args[1:])
+		fn = func(m *noid.Minter) error { return m.HoldRange(start, end) }
+
+	case "remove-range":
+		start, end := rangeFromArgs(args[1:])
+		fn = func(m *noid.Minter) error { return m.ReleaseRange(start, end) }
+
+	case "list":
+		if len(args) != 1 {
+			holdUsageError(`"hold list" takes no arguments`)
+		}
+		cmdHoldList()
+		return
+
+	default:
+		holdUsageError(fmt.Sprintf(`"hold %s" is not a valid command`, args[0]))
+	}
+
+	err := openStore("noid.db").Update(fn)
+	if err != nil {
+		holdUsageError(fmt.Sprintf("Unable to update holds: %s", err))
+	}
+}
+
+// Returns the noids in args, or reads them from stdin if args is empty
+func noidsFromArgsOrStdin(args []string) []string {
+	if len(args) > 0 {
+		return args
+	}
+
+	var ids []string
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			ids = append(ids, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		holdUsageError(fmt.Sprintf("Unable to read noids: %s", err))
+	}
+
+	return ids
+}
+
+func rangeFromArgs(args []string) (uint64, uint64) {
+	if len(args) != 2 {
+		holdUsageError("Sequence ranges require a start and end value")
+	}
+
+	start, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		holdUsageError(fmt.Sprintf(`Sequence value "%s" is not a number`, args[0]))
+	}
+	end, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		holdUsageError(fmt.Sprintf(`Sequence value "%s" is not a number`, args[1]))
+	}
+
+	return start, end
+}
+
+func cmdHoldList() {
+	m, err := openStore("noid.db").Load()
+	if err != nil {
+		holdUsageError(fmt.Sprintf("Error building minter from noid.db: %s", err))
+	}
+
+	for _, r := range m.Holds() {
+		if r.Start == r.End {
+			fmt.Println(r.Start)
+		} else {
+			fmt.Printf("%d-%d\n", r.Start, r.End)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"nerdbucket.com/go/noid/noid"
+	"os"
+	"strings"
+)
+
+func identifyUsageError(message string) {
+	fmt.Println(message)
+	fmt.Println("")
+	identifyUsage()
+	os.Exit(1)
+}
+
+func identifyUsage() {
+	fmt.Println("Usage: noid-cli identify [NAME=]TEMPLATE|DATABASE ... < noids")
+	fmt.Println("")
+}
+
+func cmdIdentifyHelp() {
+	identifyUsage()
+	fmt.Println("Reads noids from standard input, one per line, and prints the name of each")
+	fmt.Println("given template the noid is valid for.  A noid has to match a template's")
+	fmt.Println("prefix, length, characters, and check digit.  When several templates match,")
+	fmt.Println("the most specific is listed first: longer prefixes win, then templates with")
+	fmt.Println("check digits, then templates with fewer possible noids.")
+	fmt.Println("")
+	fmt.Println("Templates may be named, e.g., \"photos=ph.reeddk\"; unnamed templates are")
+	fmt.Println("listed by their template string.  An argument naming an existing file is read")
+	fmt.Println("as a noid database, which picks up settings a template string can't hold,")
+	fmt.Println("such as its check digit algorithm.  For example:")
+	fmt.Println("")
+	fmt.Println("    noid-cli identify photos=ph.reeddk maps/noid.db < noids.txt")
+	fmt.Println("")
+	fmt.Println("Exits with an error status if any noid matches no template.")
+	os.Exit(1)
+}
+
+func cmdIdentify(args []string) {
+	if len(args) < 1 {
+		identifyUsageError("At least one template is required")
+	}
+
+	r := noid.NewRegistry()
+	for _, arg := range args {
+		if err := registerIdentifyArg(r, arg); err != nil {
+			identifyUsageError(err.Error())
+		}
+	}
+
+	unmatched := false
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		id := strings.TrimSpace(scanner.Text())
+		if id == "" {
+			continue
+		}
+
+		matches := r.Identify(id)
+		if len(matches) == 0 {
+			fmt.Printf("%s: no match\n", id)
+			unmatched = true
+			continue
+		}
+
+		var names []string
+		for _, match := range matches {
+			names = append(names, match.Name)
+		}
+		fmt.Printf("%s: %s\n", id, strings.Join(names, ", "))
+	}
+	if err := scanner.Err(); err != nil {
+		identifyUsageError(fmt.Sprintf("Error reading noids: %s", err))
+	}
+
+	if unmatched {
+		os.Exit(1)
+	}
+}
+
+// Registers a database file, "NAME=TEMPLATE", or a bare template
+func registerIdentifyArg(r *noid.Registry, arg string) error {
+	if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+		m, err := openStore(arg).Load()
+		if err != nil {
+			return fmt.Errorf("Error building minter from %s: %s", arg, err)
+		}
+		return r.RegisterMinter(arg, m)
+	}
+
+	name, template := arg, arg
+	if i := strings.Index(arg, "="); i != -1 {
+		name, template = arg[:i], arg[i+1:]
+	}
+
+	t, err := noid.NewTemplate(template)
+	if err != nil {
+		return fmt.Errorf("Invalid template %#v: %s", template, err)
+	}
+	return r.Register(name, t)
+}
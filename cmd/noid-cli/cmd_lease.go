@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"nerdbucket.com/go/noid/noid"
+	"os"
+	"strconv"
+	"time"
+)
+
+func leaseUsageError(message string) {
+	fmt.Println(message)
+	fmt.Println("")
+	leaseUsage()
+	os.Exit(1)
+}
+
+func leaseUsage() {
+	fmt.Println("Usage: noid-cli lease grant CLIENT COUNT DURATION FILE")
+	fmt.Println("       noid-cli lease return FILE")
+	fmt.Println("       noid-cli lease expire")
+	fmt.Println("       noid-cli lease list")
+	fmt.Println("")
+}
+
+func cmdLeaseHelp() {
+	leaseUsage()
+	fmt.Println("Hands ranges of the noid database's sequence to clients which mint while")
+	fmt.Println(`disconnected.  "grant" reserves COUNT sequence values for CLIENT, good for`)
+	fmt.Println(`DURATION (e.g., "72h"), and writes a lease file the client mints from with`)
+	fmt.Println(`"noid-cli mint next --lease FILE".  Nothing else mints from a leased range.`)
+	fmt.Println("")
+	fmt.Println(`"return" closes a lease file so it can't mint again, then hands its unused`)
+	fmt.Println("values, and anything bound to the noids it minted, back to noid.db.")
+	fmt.Println(`"expire" drops leases past their expiry; their unused values are never`)
+	fmt.Println("minted, since there's no telling how much of them the client used, e.g.:")
+	fmt.Println("")
+	fmt.Println("    noid-cli lease grant laptop-3 500 72h laptop-3.lease")
+	fmt.Println("    noid-cli mint next --lease laptop-3.lease")
+	fmt.Println("    noid-cli lease return laptop-3.lease")
+	os.Exit(1)
+}
+
+func cmdLease(args []string) {
+	if len(args) < 1 {
+		leaseUsageError("Lease command requires a sub-command")
+	}
+
+	switch args[0] {
+	case "grant":
+		if len(args) != 5 {
+			leaseUsageError(`"lease grant" takes 4 arguments`)
+		}
+		cmdLeaseGrant(args[1:])
+
+	case "return":
+		if len(args) != 2 {
+			leaseUsageError(`"lease return" takes 1 argument`)
+		}
+		cmdLeaseReturn(args[1])
+
+	case "expire":
+		if len(args) != 1 {
+			leaseUsageError(`"lease expire" takes no arguments`)
+		}
+		cmdLeaseExpire()
+
+	case "list":
+		if len(args) != 1 {
+			leaseUsageError(`"lease list" takes no arguments`)
+		}
+		cmdLeaseList()
+
+	default:
+		leaseUsageError(fmt.Sprintf(`"lease %s" is not a valid command`, args[0]))
+	}
+}
+
+func cmdLeaseGrant(args []string) {
+	client, filename := args[0], args[3]
+	count, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		leaseUsageError(fmt.Sprintf(`Invalid count "%s"`, args[1]))
+	}
+	duration, err := time.ParseDuration(args[2])
+	if err != nil || duration <= 0 {
+		leaseUsageError(fmt.Sprintf(`Invalid duration "%s": expected a value like "72h"`, args[2]))
+	}
+	if _, err = os.Stat(filename); err == nil {
+		leaseUsageError(fmt.Sprintf("Lease file %s already exists", filename))
+	}
+
+	// noid.db is saved before the lease file is written: if writing the lease
+	// file fails, the range is wasted, but never minted twice
+	var c *noid.Minter
+	err = openStore("noid.db").Update(func(m *noid.Minter) error {
+		var err error
+		c, err = m.GrantLease(client, count, time.Now().Add(duration))
+		return err
+	})
+	if err != nil {
+		leaseUsageError(fmt.Sprintf("Unable to grant lease: %s", err))
+	}
+
+	err = openStore(filename).CreateFromMinter(c)
+	if err != nil {
+		leaseUsageError(fmt.Sprintf("Lease granted, but unable to write %s: %s", filename, err))
+	}
+
+	l := c.Lease()
+	fmt.Printf("Leased sequence values %d-%d to %s until %s\n", l.Start, l.End, l.Client, l.Expires.Format(time.RFC3339))
+}
+
+func cmdLeaseReturn(filename string) {
+	// The lease file is closed first, so a failure updating noid.db can only
+	// waste the remainder
+	lf := openStore(filename)
+	err := lf.Update(func(c *noid.Minter) error { return c.CloseLease() })
+	if err != nil {
+		leaseUsageError(fmt.Sprintf("Unable to close %s: %s", filename, err))
+	}
+	c, err := lf.Load()
+	if err != nil {
+		leaseUsageError(fmt.Sprintf("Error reading %s: %s", filename, err))
+	}
+
+	err = openStore("noid.db").Update(func(m *noid.Minter) error { return m.ReturnLease(c) })
+	if err != nil {
+		leaseUsageError(fmt.Sprintf("Unable to return lease: %s", err))
+	}
+}
+
+func cmdLeaseExpire() {
+	var expired []noid.Lease
+	err := openStore("noid.db").Update(func(m *noid.Minter) error {
+		expired = m.ExpireLeases()
+		return nil
+	})
+	if err != nil {
+		leaseUsageError(fmt.Sprintf("Unable to expire leases: %s", err))
+	}
+
+	for _, l := range expired {
+		fmt.Printf("Expired %s's lease of sequence values %d-%d\n", l.Client, l.Start, l.End)
+	}
+}
+
+func cmdLeaseList() {
+	m, err := openStore("noid.db").Load()
+	if err != nil {
+		leaseUsageError(fmt.Sprintf("Error building minter from noid.db: %s", err))
+	}
+
+	for _, l := range m.Leases() {
+		fmt.Printf("%s: %d-%d, expires %s\n", l.Client, l.Start, l.End, l.Expires.Format(time.RFC3339))
+	}
+	for _, r := range m.Returned() {
+		fmt.Printf("returned: %d-%d\n", r.Start, r.End)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"nerdbucket.com/go/noid/noid"
 	"os"
 	"strconv"
+	"strings"
 )
 
 func mintUsageError(message string) {
@@ -17,8 +18,9 @@ func mintUsageError(message string) {
 func mintUsage() {
 	fmt.Println("Usage: noid-cli mint immediate TEMPLATE SEQUENCE")
 	fmt.Println("")
-	fmt.Println("Usage: noid-cli mint init TEMPLATE")
-	fmt.Println("       noid-cli mint next")
+	fmt.Println("Usage: noid-cli mint init TEMPLATE [options]")
+	fmt.Println("       noid-cli mint next [--lease FILE] [--bind KEY=VALUE ...]")
+	fmt.Println("       noid-cli mint peek COUNT")
 	fmt.Println("")
 }
 
@@ -36,6 +38,34 @@ func cmdMintHelp() {
 	fmt.Println("    noid-cli mint init reedeek       # Creates noid.db with serialized minter")
 	fmt.Println("    noid-cli mint next               # Prints out q67j4g")
 	fmt.Println("    noid-cli mint next               # Prints out y67j4r")
+	fmt.Println("")
+	fmt.Println(`"init" accepts these options:`)
+	fmt.Println("")
+	fmt.Println(templateOptionsHelp)
+	fmt.Println("    --shard ID/COUNT     Mints only shard ID of COUNT, so minters at sites")
+	fmt.Println("                         which can't talk to each other never collide, e.g.,")
+	fmt.Println(`                         "--shard 0/3", "--shard 1/3", and "--shard 2/3"`)
+	fmt.Println("")
+	fmt.Println(`Templates starting with "ark:/", a NAAN, and an optional shoulder mint ARKs`)
+	fmt.Println("whose check digit covers the NAAN, e.g.:")
+	fmt.Println("")
+	fmt.Println("    noid-cli mint immediate ark:/12345/x5reedeek 0   # Prints ark:/12345/x5q67j4t")
+	fmt.Println("")
+	fmt.Println("A prefix may contain the date placeholders {YYYY}, {MM}, and {DD}.  Each")
+	fmt.Println("date gets its own sequence, which starts over when the date changes, e.g.:")
+	fmt.Println("")
+	fmt.Println(`    noid-cli mint init "{YYYY}.seee" # "next" prints 2026.000, 2026.001, ...`)
+	fmt.Println("")
+	fmt.Println(`Any number of "--bind KEY=VALUE" options may be given to "next", in which`)
+	fmt.Println("case the noid and its bindings are written to noid.db together, e.g.:")
+	fmt.Println("")
+	fmt.Println("    noid-cli mint next --bind who=Jeremy --bind what=Photograph")
+	fmt.Println("")
+	fmt.Println(`"--lease FILE" mints from a lease file instead of noid.db, for clients`)
+	fmt.Println(`working offline; see "noid-cli help lease".`)
+	fmt.Println("")
+	fmt.Println(`The "peek" sub-command prints the next COUNT noids "next" would mint without`)
+	fmt.Println("changing noid.db.")
 	os.Exit(1)
 }
 
@@ -74,17 +104,22 @@ func cmdMint(args []string) {
 
 	case "init":
 		fn = cmdCreateDatabase
-		argCount = 1
+		argCount = -1
 
 	case "next":
 		fn = cmdMintNext
+		argCount = -1
+
+	case "peek":
+		fn = cmdMintPeek
+		argCount = 1
 	}
 
 	if fn == nil {
 		mintUsageError(fmt.Sprintf(`"mint %s" is not a valid command`, args[0]))
 	}
 
-	if argCount+1 != len(args) {
+	if argCount >= 0 && argCount+1 != len(args) {
 		mintUsageError(fmt.Sprintf(`"mint %s" takes %d arguments`, args[0], argCount))
 	}
 
@@ -100,34 +135,105 @@ func cmdMintImmediate(args []string) {
 }
 
 func cmdCreateDatabase(args []string) {
-	// First make sure we can create the file - it MUST be a new file
-	f, err := os.OpenFile("noid.db", os.O_CREATE|os.O_EXCL|os.O_RDWR, 0660)
+	// The shard is a minter option, not a template option, so it's pulled out
+	// before the template is parsed
+	var shardID, shardCount uint64 = 0, 1
+	var templateArgs []string
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--shard" {
+			templateArgs = append(templateArgs, args[i])
+			continue
+		}
+		if i+1 == len(args) {
+			mintUsageError(`Option "--shard" requires a value`)
+		}
+		i++
+		shardID, shardCount = shardFromArg(args[i])
+	}
+
+	// Make sure the template is legit before we bother with the file
+	template := templateFromArgs(templateArgs, mintUsageError)
+	m, err := noid.NewShardedMinter(template, shardID, shardCount)
 	if err != nil {
-		mintUsageError(fmt.Sprintf("Unable to create noid.db: %s", err))
+		mintUsageError(fmt.Sprintf("Unable to create a minter with template %s: %s", template, err))
 	}
-	defer f.Close()
 
-	// Now make sure the template is legit
-	template := args[0]
-	m, err := noid.NewMinter(template)
+	err = openStore("noid.db").CreateFromMinter(m)
 	if err != nil {
-		mintUsageError(fmt.Sprintf("Unable to create a minter with template %s: %s", err))
+		mintUsageError(fmt.Sprintf("Unable to create noid.db: %s", err))
+	}
+}
+
+// Parses "ID/COUNT", e.g., "0/3" for the first of three shards
+func shardFromArg(arg string) (uint64, uint64) {
+	parts := strings.SplitN(arg, "/", 2)
+	if len(parts) == 2 {
+		id, err1 := strconv.ParseUint(parts[0], 10, 64)
+		count, err2 := strconv.ParseUint(parts[1], 10, 64)
+		if err1 == nil && err2 == nil {
+			return id, count
+		}
+	}
+
+	mintUsageError(fmt.Sprintf(`Invalid shard %#v: expected "ID/COUNT", e.g., "0/3"`, arg))
+	return 0, 0
+}
+
+// Parses "--bind KEY=VALUE" pairs into a set of bindings
+func bindingsFromArgs(args []string) noid.Bindings {
+	b := make(noid.Bindings)
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--bind" || i+1 == len(args) {
+			mintUsageError(fmt.Sprintf(`Invalid argument %#v: expected "--bind KEY=VALUE"`, args[i]))
+		}
+
+		i++
+		parts := strings.SplitN(args[i], "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			mintUsageError(fmt.Sprintf(`Invalid binding %#v: expected "KEY=VALUE"`, args[i]))
+		}
+		b[parts[0]] = parts[1]
+	}
+
+	return b
+}
+
+func cmdMintNext(args []string) {
+	// The lease file, like the shard, isn't a binding, so it's pulled out first
+	filename := "noid.db"
+	var bindArgs []string
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--lease" {
+			bindArgs = append(bindArgs, args[i])
+			continue
+		}
+		if i+1 == len(args) {
+			mintUsageError(`Option "--lease" requires a value`)
+		}
+		i++
+		filename = args[i]
 	}
 
-	m.WriteJSON(f)
+	b := bindingsFromArgs(bindArgs)
+	id, err := openStore(filename).MintAndBind(b)
+	if err != nil {
+		mintUsageError(fmt.Sprintf("Unable to mint from %s: %s", filename, err))
+	}
+	fmt.Println(id)
 }
 
-func cmdMintNext([]string) {
-	m, err := noid.NewMinterFromJSONFile("noid.db")
+func cmdMintPeek(args []string) {
+	count, err := strconv.Atoi(args[0])
+	if err != nil || count < 0 {
+		mintUsageError(fmt.Sprintf(`Unable to peek: count "%s" is not a valid number`, args[0]))
+	}
+
+	m, err := openStore("noid.db").Load()
 	if err != nil {
 		mintUsageError(fmt.Sprintf("Error building minter from noid.db: %s", err))
 	}
-	fmt.Println(m.Mint())
 
-	f, err := os.OpenFile("noid.db", os.O_RDWR, 0660)
-	if err != nil {
-		mintUsageError(fmt.Sprintf("Unable to re-serialize minter: %s", err))
+	for _, id := range m.Peek(count) {
+		fmt.Println(id)
 	}
-	defer f.Close()
-	m.WriteJSON(f)
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"nerdbucket.com/go/noid/noid"
+	"os"
+)
+
+func recoverUsageError(message string) {
+	fmt.Println(message)
+	fmt.Println("")
+	recoverUsage()
+	os.Exit(1)
+}
+
+func recoverUsage() {
+	fmt.Println("Usage: noid-cli recover TEMPLATE [options] < noids.txt")
+	fmt.Println("")
+}
+
+func cmdRecoverHelp() {
+	recoverUsage()
+	fmt.Println("Rebuilds a lost noid database from noids which have already been minted.")
+	fmt.Println("Noids are read from standard input, one per line, and a new noid.db is")
+	fmt.Println("created in the current working directory whose sequence is past the highest")
+	fmt.Println("one found.  Lines which aren't valid for the template are reported but")
+	fmt.Println("otherwise ignored, e.g.:")
+	fmt.Println("")
+	fmt.Println("    noid-cli recover reedeek < all-our-noids.txt")
+	fmt.Println("")
+	fmt.Println("The template options must match those used to mint the noids:")
+	fmt.Println("")
+	fmt.Println(templateOptionsHelp)
+	os.Exit(1)
+}
+
+func cmdRecover(args []string) {
+	if len(args) < 1 {
+		recoverUsageError("Recover command requires a template")
+	}
+
+	t := templateFromArgs(args, recoverUsageError)
+	m, badLines, err := noid.RecoverTemplate(t, os.Stdin)
+	if err != nil {
+		recoverUsageError(fmt.Sprintf("Unable to recover: %s", err))
+	}
+
+	for _, bad := range badLines {
+		fmt.Fprintf(os.Stderr, "Skipped %s\n", bad)
+	}
+
+	err = openStore("noid.db").CreateFromMinter(m)
+	if err != nil {
+		recoverUsageError(fmt.Sprintf("Unable to create noid.db: %s", err))
+	}
+
+	fmt.Printf("Recovered noid.db with sequence %d\n", m.Sequence())
+}
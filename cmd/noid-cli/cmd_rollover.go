@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"nerdbucket.com/go/noid/noid"
+	"os"
+)
+
+func rolloverUsageError(message string) {
+	fmt.Println(message)
+	fmt.Println("")
+	rolloverUsage()
+	os.Exit(1)
+}
+
+func rolloverUsage() {
+	fmt.Println("Usage: noid-cli rollover set [TEMPLATE ...]")
+	fmt.Println("       noid-cli rollover list")
+	fmt.Println("")
+}
+
+func cmdRolloverHelp() {
+	rolloverUsage()
+	fmt.Println("Manages the chain of templates the noid database in the current working")
+	fmt.Println("directory switches to, in order, whenever its template runs out.  Each")
+	fmt.Println("template in the chain uses the current template's check digit algorithm and")
+	fmt.Println(`grouping.  "set" rejects any chain where two templates, including ones`)
+	fmt.Println(`already used up, could mint the same noid.  "set" with no templates clears`)
+	fmt.Println(`the chain.  "list" prints every template used so far, then the chain, e.g.:`)
+	fmt.Println("")
+	fmt.Println("    noid-cli rollover set x.reeedeek x.reeeedeek")
+	fmt.Println("    noid-cli rollover list")
+	fmt.Println("")
+	fmt.Println("Noids from every template the database has used can still be validated and")
+	fmt.Println("bound.")
+	os.Exit(1)
+}
+
+func cmdRollover(args []string) {
+	if len(args) < 1 {
+		rolloverUsageError("Rollover command requires a sub-command")
+	}
+
+	switch args[0] {
+	case "set":
+		cmdRolloverSet(args[1:])
+
+	case "list":
+		if len(args) != 1 {
+			rolloverUsageError(`"rollover list" takes no arguments`)
+		}
+		cmdRolloverList()
+
+	default:
+		rolloverUsageError(fmt.Sprintf(`"rollover %s" is not a valid command`, args[0]))
+	}
+}
+
+func cmdRolloverSet(args []string) {
+	err := openStore("noid.db").Update(func(m *noid.Minter) error {
+		gens := m.Generations()
+		current := gens[len(gens)-1]
+
+		var chain []*noid.Template
+		for _, arg := range args {
+			t, err := noid.NewTemplate(arg)
+			if err != nil {
+				return fmt.Errorf("Invalid template %#v: %s", arg, err)
+			}
+			t.CheckDigit = current.CheckDigit
+			t.GroupSize = current.GroupSize
+			t.GroupSeparator = current.GroupSeparator
+			t.GroupFromRight = current.GroupFromRight
+			chain = append(chain, t)
+		}
+		return m.SetRollover(chain...)
+	})
+	if err != nil {
+		rolloverUsageError(fmt.Sprintf("Unable to set rollover chain: %s", err))
+	}
+}
+
+func cmdRolloverList() {
+	m, err := openStore("noid.db").Load()
+	if err != nil {
+		rolloverUsageError(fmt.Sprintf("Error building minter from noid.db: %s", err))
+	}
+
+	gens := m.Generations()
+	for i, t := range gens {
+		state := "used"
+		if i == len(gens)-1 {
+			state = "current"
+		}
+		fmt.Printf("%d: %s (%s)\n", i, t, state)
+	}
+	for i, t := range m.Rollover() {
+		fmt.Printf("%d: %s (next)\n", len(gens)+i, t)
+	}
+}
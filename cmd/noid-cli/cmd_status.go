@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"nerdbucket.com/go/noid/noid"
+	"os"
+	"strings"
+	"time"
+)
+
+func statusUsageError(message string) {
+	fmt.Println(message)
+	fmt.Println("")
+	statusUsage()
+	os.Exit(1)
+}
+
+func statusUsage() {
+	fmt.Println("Usage: noid-cli status get NOID")
+	fmt.Println("       noid-cli status set NOID STATUS [REASON]")
+	fmt.Println("")
+}
+
+func cmdStatusHelp() {
+	statusUsage()
+	fmt.Println("Queries or changes the lifecycle status of a noid minted from the noid")
+	fmt.Println(`database in the current working directory.  Valid statuses are "reserved",`)
+	fmt.Println(`"public", "unavailable", and "withdrawn".  Every minted noid starts out`)
+	fmt.Println("reserved, and withdrawing a noid is permanent and requires a reason, e.g.:")
+	fmt.Println("")
+	fmt.Println("    noid-cli status set q67j4g public")
+	fmt.Println(`    noid-cli status set q67j4g withdrawn "Duplicate of y67j4r"`)
+	fmt.Println("    noid-cli status get q67j4g")
+	os.Exit(1)
+}
+
+func cmdStatus(args []string) {
+	if len(args) < 1 {
+		statusUsageError("Status command requires a sub-command")
+	}
+
+	switch args[0] {
+	case "get":
+		if len(args) != 2 {
+			statusUsageError(`"status get" takes 1 argument`)
+		}
+		cmdStatusGet(args[1])
+
+	case "set":
+		if len(args) != 3 && len(args) != 4 {
+			statusUsageError(`"status set" takes 2 or 3 arguments`)
+		}
+		reason := ""
+		if len(args) == 4 {
+			reason = args[3]
+		}
+		cmdStatusSet(args[1], args[2], reason)
+
+	default:
+		statusUsageError(fmt.Sprintf(`"status %s" is not a valid command`, args[0]))
+	}
+}
+
+func cmdStatusGet(id string) {
+	m, err := openStore("noid.db").Load()
+	if err != nil {
+		statusUsageError(fmt.Sprintf("Error building minter from noid.db: %s", err))
+	}
+
+	l, err := m.Status(id)
+	if err != nil {
+		statusUsageError(fmt.Sprintf("Unable to get status: %s", err))
+	}
+
+	fmt.Printf("%s: %s\n", id, l.Status)
+	if l.Reason != "" {
+		fmt.Printf("Reason: %s\n", l.Reason)
+	}
+	if l.Tombstone != nil {
+		fmt.Printf("Withdrawn: %s\n", l.Tombstone.Format(time.RFC3339))
+	}
+	for _, c := range l.History {
+		line := []string{c.Time.Format(time.RFC3339), c.From.String(), "->", c.To.String()}
+		if c.Reason != "" {
+			line = append(line, fmt.Sprintf("(%s)", c.Reason))
+		}
+		fmt.Println("    " + strings.Join(line, " "))
+	}
+}
+
+func cmdStatusSet(id, statusName, reason string) {
+	s, err := noid.ParseStatus(statusName)
+	if err != nil {
+		statusUsageError(err.Error())
+	}
+
+	err = openStore("noid.db").Update(func(m *noid.Minter) error {
+		return m.SetStatus(id, s, reason, time.Now())
+	})
+	if err != nil {
+		statusUsageError(fmt.Sprintf("Unable to set status: %s", err))
+	}
+}
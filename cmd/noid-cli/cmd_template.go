@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func templateUsageError(message string) {
+	fmt.Println(message)
+	fmt.Println("")
+	templateUsage()
+	os.Exit(1)
+}
+
+func templateUsage() {
+	fmt.Println("Usage: noid-cli template export --format FORMAT [--column NAME] [TEMPLATE [options]]")
+	fmt.Println("")
+}
+
+func cmdTemplateHelp() {
+	templateUsage()
+	fmt.Println("Prints a pattern matching the noids a template mints, so that other systems")
+	fmt.Println("can validate them.  If no template is given, the template in the current")
+	fmt.Println("working directory's noid database is used.  FORMAT is one of:")
+	fmt.Println("")
+	fmt.Println("    regex        An anchored regular expression (Go / RE2 syntax)")
+	fmt.Println(`    pcre         The same, anchored with \A and \z for PCRE and friends`)
+	fmt.Println("    postgres     A CHECK constraint on the column given by --column")
+	fmt.Println(`                 (default "id")`)
+	fmt.Println("    jsonschema   A JSON Schema for a string with the pattern")
+	fmt.Println("")
+	fmt.Println("Patterns can't verify check digits, only that they're characters the")
+	fmt.Println("algorithm could produce.  ARKs are matched in their normalized form.")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("")
+	fmt.Println(templateOptionsHelp)
+	os.Exit(1)
+}
+
+func cmdTemplate(args []string) {
+	if len(args) < 1 {
+		templateUsageError("Template command requires a sub-command")
+	}
+	if args[0] != "export" {
+		templateUsageError(fmt.Sprintf(`"template %s" is not a valid command`, args[0]))
+	}
+
+	format, column := "", "id"
+	var rest []string
+	for i := 1; i < len(args); i++ {
+		if (args[i] == "--format" || args[i] == "--column") && i+1 == len(args) {
+			templateUsageError(fmt.Sprintf("Option %#v requires a value", args[i]))
+		}
+
+		switch args[i] {
+		case "--format":
+			format = args[i+1]
+			i++
+		case "--column":
+			column = args[i+1]
+			i++
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	if format == "" {
+		templateUsageError("--format is required")
+	}
+
+	var pattern string
+	if len(rest) == 0 {
+		m, err := openStore("noid.db").Load()
+		if err != nil {
+			templateUsageError(fmt.Sprintf("Error building minter from noid.db: %s", err))
+		}
+		pattern = m.RegexpPattern()
+	} else {
+		pattern = templateFromArgs(rest, templateUsageError).RegexpPattern()
+	}
+
+	switch format {
+	case "regex":
+		fmt.Println(pattern)
+
+	case "pcre":
+		fmt.Println(`\A` + strings.TrimSuffix(strings.TrimPrefix(pattern, "^"), "$") + `\z`)
+
+	case "postgres":
+		fmt.Printf("CHECK (%s ~ '%s')\n", quoteIdentifier(column), strings.Replace(pattern, "'", "''", -1))
+
+	case "jsonschema":
+		schema := struct {
+			Type    string `json:"type"`
+			Pattern string `json:"pattern"`
+		}{"string", pattern}
+		out, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			templateUsageError(err.Error())
+		}
+		fmt.Println(string(out))
+
+	default:
+		templateUsageError(fmt.Sprintf("Unknown format %#v", format))
+	}
+}
+
+// Double-quotes a PostgreSQL identifier unless it's a plain lowercase name
+func quoteIdentifier(name string) string {
+	plain := name != ""
+	for i, char := range name {
+		if !(char >= 'a' && char <= 'z' || char == '_' || i > 0 && char >= '0' && char <= '9') {
+			plain = false
+		}
+	}
+	if plain {
+		return name
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func validateUsageError(message string) {
+	fmt.Println(message)
+	fmt.Println("")
+	validateUsage()
+	os.Exit(1)
+}
+
+func validateUsage() {
+	fmt.Println("Usage: noid-cli validate [NOID ...]")
+	fmt.Println("")
+}
+
+func cmdValidateHelp() {
+	validateUsage()
+	fmt.Println("Checks hand-typed noids against the template in the current working")
+	fmt.Println("directory's noid database.  Case, spaces, and hyphens are ignored, and")
+	fmt.Println(`commonly confused characters are fixed ("O" for zero, "I" or "L" for one).`)
+	fmt.Println("Noids are read from standard input, one per line, if none are given.  Each")
+	fmt.Println("valid noid is printed in its canonical form, noting any corrections, e.g.:")
+	fmt.Println("")
+	fmt.Println("    noid-cli validate Q67J-4G     # Prints Q67J-4G: q67j4g (corrected)")
+	fmt.Println("")
+	fmt.Println("If the template has a check digit, a corrected noid is only accepted when the")
+	fmt.Println("check digit confirms the correction.  Exits with an error status if any noid")
+	fmt.Println("is invalid.")
+	os.Exit(1)
+}
+
+func cmdValidate(args []string) {
+	m, err := openStore("noid.db").Load()
+	if err != nil {
+		validateUsageError(fmt.Sprintf("Error building minter from noid.db: %s", err))
+	}
+
+	failed := false
+	for _, input := range noidsFromArgsOrStdin(args) {
+		id, corrected, err := m.ValidateInput(input)
+		switch {
+		case err != nil:
+			fmt.Printf("%s: invalid: %s\n", input, err)
+			failed = true
+		case corrected:
+			fmt.Printf("%s: %s (corrected)\n", input, id)
+		default:
+			fmt.Printf("%s: %s\n", input, id)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
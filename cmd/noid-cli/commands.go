@@ -11,4 +11,15 @@ var commands = make(map[string]*Command)
 func initCommands() {
 	commands["help"] = &Command{handler: cmdHelp, helpHandler: cmdHelpHelp, helpSummary: "Displays this usage page"}
 	commands["mint"] = &Command{handler: cmdMint, helpHandler: cmdMintHelp, helpSummary: "Controls minting of noids"}
+	commands["identify"] = &Command{handler: cmdIdentify, helpHandler: cmdIdentifyHelp, helpSummary: "Finds which templates a noid could have come from"}
+	commands["db"] = &Command{handler: cmdDb, helpHandler: cmdDbHelp, helpSummary: "Verifies and migrates noid database files"}
+	commands["block"] = &Command{handler: cmdBlock, helpHandler: cmdBlockHelp, helpSummary: "Manages substrings minted noids must not contain"}
+	commands["extract"] = &Command{handler: cmdExtract, helpHandler: cmdExtractHelp, helpSummary: "Finds noids in free text"}
+	commands["lease"] = &Command{handler: cmdLease, helpHandler: cmdLeaseHelp, helpSummary: "Hands sequence ranges to clients which mint offline"}
+	commands["hold"] = &Command{handler: cmdHold, helpHandler: cmdHoldHelp, helpSummary: "Manages noids the minter must never mint"}
+	commands["recover"] = &Command{handler: cmdRecover, helpHandler: cmdRecoverHelp, helpSummary: "Rebuilds noid.db from already-minted noids"}
+	commands["template"] = &Command{handler: cmdTemplate, helpHandler: cmdTemplateHelp, helpSummary: "Exports a template as a pattern other systems can check"}
+	commands["rollover"] = &Command{handler: cmdRollover, helpHandler: cmdRolloverHelp, helpSummary: "Sets the templates to switch to when the current one runs out"}
+	commands["status"] = &Command{handler: cmdStatus, helpHandler: cmdStatusHelp, helpSummary: "Queries or changes a noid's lifecycle status"}
+	commands["validate"] = &Command{handler: cmdValidate, helpHandler: cmdValidateHelp, helpSummary: "Checks and corrects hand-typed noids"}
 }
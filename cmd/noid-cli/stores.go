@@ -0,0 +1,26 @@
+package main
+
+import (
+	"nerdbucket.com/go/noid/noid"
+)
+
+// Returns the store for the given file, first registering any custom
+// alphabets its minter uses.  Decoding a minter never registers alphabets on
+// its own, but the CLI's files were written by the CLI's own "--alphabet"
+// options, so they're trusted.  Files which can't be read are left for the
+// store to report on.
+func openStore(filename string) *noid.Store {
+	s := noid.NewStore(filename)
+	alphabets, err := s.Alphabets()
+	if err != nil {
+		return s
+	}
+
+	for char, alphabet := range alphabets {
+		runes := []rune(char)
+		if len(runes) == 1 && noid.Alphabet(runes[0]) == "" {
+			noid.RegisterAlphabet(runes[0], alphabet)
+		}
+	}
+	return s
+}
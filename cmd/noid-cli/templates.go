@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"nerdbucket.com/go/noid/noid"
+	"strconv"
+	"strings"
+)
+
+// Describes the template options shared by commands which build a minter
+const templateOptionsHelp = `    --check-digit NAME   Check digit algorithm for templates ending in "k":
+                         legacy (the default), ncda, iso7064-mod11-2, damm,
+                         or urn-nbn; iso7064-mod11-2 and damm only work with
+                         masks of digits, like "sddddk"
+    --alphabet C=CHARS   Registers mask character C to mean one of CHARS, e.g.,
+                         "--alphabet h=0123456789abcdef" for hex digits
+    --group SIZE         Splits minted noids into groups of SIZE characters for
+                         display, e.g., "x5gt-7k3m-q"
+    --group-separator S  Separator between groups (default "-")
+    --group-from SIDE    Counts groups from the "left" (default) or "right"
+    --separator S        Treats the end of the prefix as separator S, e.g.,
+                         "--separator +" for "foo+reedeek", or "" for none;
+                         only ".", "/", ":", "-", and "_" are found on their own`
+
+// Parses "TEMPLATE [options]" into a template, calling usageError if anything
+// is wrong
+func templateFromArgs(args []string, usageError func(string)) *noid.Template {
+	if len(args) < 1 {
+		usageError("A template is required")
+	}
+
+	var checkDigit noid.CheckDigit
+	var groupSize int
+	var groupSeparator string
+	var groupFromRight bool
+	var separator *string
+	var err error
+	for i := 1; i < len(args); i++ {
+		if i+1 == len(args) {
+			usageError(fmt.Sprintf("Option %#v requires a value", args[i]))
+		}
+
+		opt, val := args[i], args[i+1]
+		i++
+
+		switch opt {
+		case "--check-digit":
+			checkDigit, err = noid.CheckDigitByName(val)
+			if err != nil {
+				usageError(err.Error())
+			}
+
+		// Alphabets have to be registered before the template is parsed
+		case "--alphabet":
+			parts := strings.SplitN(val, "=", 2)
+			if len(parts) != 2 || len([]rune(parts[0])) != 1 {
+				usageError(fmt.Sprintf(`Invalid alphabet %#v: expected "C=CHARS"`, val))
+			}
+			if err = noid.RegisterAlphabet([]rune(parts[0])[0], parts[1]); err != nil {
+				usageError(err.Error())
+			}
+
+		case "--group":
+			groupSize, err = strconv.Atoi(val)
+			if err != nil || groupSize < 1 {
+				usageError(fmt.Sprintf("Invalid group size %#v", val))
+			}
+
+		case "--group-separator":
+			groupSeparator = val
+
+		case "--separator":
+			separator = &val
+
+		case "--group-from":
+			switch val {
+			case "left":
+				groupFromRight = false
+			case "right":
+				groupFromRight = true
+			default:
+				usageError(fmt.Sprintf(`Invalid group side %#v: expected "left" or "right"`, val))
+			}
+
+		default:
+			usageError(fmt.Sprintf("Unknown option %#v", opt))
+		}
+	}
+
+	t, err := noid.NewTemplate(args[0])
+	if err != nil {
+		usageError(fmt.Sprintf("Invalid template %#v: %s", args[0], err))
+	}
+	t.CheckDigit = checkDigit
+	t.GroupSize = groupSize
+	t.GroupSeparator = groupSeparator
+	t.GroupFromRight = groupFromRight
+	if separator != nil {
+		if err = t.SetSeparator(*separator); err != nil {
+			usageError(err.Error())
+		}
+	}
+
+	return t
+}
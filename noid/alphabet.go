@@ -0,0 +1,150 @@
+package noid
+
+// This file handles the alphabets used for each mask character
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// A maskCharacter is the alphabet a mask character draws from.  Alphabets are
+// always a power of two in size so each character holds a fixed number of
+// bits.
+type maskCharacter struct {
+	alphabet []rune
+	bits     byte
+}
+
+var maskCharactersMu sync.RWMutex
+var maskCharacters = map[rune]*maskCharacter{
+	'd': {alphabet: []rune(ExtendedDigits[:1<<DigitBits]), bits: DigitBits},
+	'e': {alphabet: []rune(ExtendedDigits), bits: ExtendedDigitBits},
+	'c': {alphabet: []rune(ProquintConsonants), bits: 4},
+	'v': {alphabet: []rune(ProquintVowels), bits: 2},
+}
+
+// Mask characters every minter knows about, so they never need to be saved
+// with a minter's state
+const builtinMaskCharacters = "decv"
+
+// Characters which already mean something in a template string
+const reservedMaskCharacters = "rszk"
+
+// RegisterAlphabet binds a new mask character to the given alphabet, so that,
+// for instance, after registering 'h' with "0123456789abcdef", the template
+// "reehhh" would mint noids ending in three hex digits.
+//
+// The mask character must be an ASCII letter which isn't already in use.  The
+// alphabet can't have duplicate characters, and its size must be a power of
+// two from 2 to 65536, since every mask character holds a fixed number of
+// bits.  Digits-only alphabets therefore have to be something like
+// "01234567" rather than all ten digits.
+//
+// Templates using the new character won't parse until it's registered, so
+// this is generally done in an init function.
+func RegisterAlphabet(char rune, alphabet string) error {
+	if !(char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z') {
+		return fmt.Errorf("Mask character %#v must be an ASCII letter", string(char))
+	}
+	for _, r := range reservedMaskCharacters {
+		if char == r {
+			return fmt.Errorf("Mask character %#v is reserved", string(char))
+		}
+	}
+
+	mc, err := newMaskCharacter(alphabet)
+	if err != nil {
+		return err
+	}
+
+	maskCharactersMu.Lock()
+	defer maskCharactersMu.Unlock()
+	if maskCharacters[char] != nil {
+		return fmt.Errorf("Mask character %#v is already registered", string(char))
+	}
+	maskCharacters[char] = mc
+
+	return nil
+}
+
+// Alphabet returns the alphabet for a mask character, or an empty string if
+// it isn't registered
+func Alphabet(char rune) string {
+	mc := lookupMaskCharacter(char)
+	if mc == nil {
+		return ""
+	}
+	return string(mc.alphabet)
+}
+
+func newMaskCharacter(alphabet string) (*maskCharacter, error) {
+	runes := []rune(alphabet)
+	size := len(runes)
+	if size < 2 || size > 1<<16 || size&(size-1) != 0 {
+		return nil, fmt.Errorf("Alphabet %#v has %d characters, but must have a power of two from 2 to 65536", alphabet, size)
+	}
+
+	seen := make(map[rune]bool)
+	for _, r := range runes {
+		if seen[r] {
+			return nil, fmt.Errorf("Alphabet %#v has duplicate character %#v", alphabet, string(r))
+		}
+		seen[r] = true
+	}
+
+	var bits byte
+	for 1<<bits < size {
+		bits++
+	}
+
+	return &maskCharacter{alphabet: runes, bits: bits}, nil
+}
+
+func lookupMaskCharacter(char rune) *maskCharacter {
+	maskCharactersMu.RLock()
+	defer maskCharactersMu.RUnlock()
+	return maskCharacters[char]
+}
+
+func isMaskCharacter(char rune) bool {
+	return lookupMaskCharacter(char) != nil
+}
+
+// Returns the index of char in the alphabet, or -1 if it isn't there
+func (mc *maskCharacter) indexOf(char rune) int {
+	for i, r := range mc.alphabet {
+		if r == char {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns an error unless char is registered with the given alphabet
+func checkAlphabet(char rune, alphabet string) error {
+	existing := Alphabet(char)
+	if existing == "" {
+		return fmt.Errorf("Mask character %#v isn't registered; register it as %#v to accept this template", string(char), alphabet)
+	}
+	if existing != alphabet {
+		return fmt.Errorf("Mask character %#v is registered as %#v, not %#v", string(char), existing, alphabet)
+	}
+	return nil
+}
+
+// Returns the alphabets for any custom mask characters in the template
+func (t *Template) customAlphabets() map[string]string {
+	var alphabets map[string]string
+	for _, char := range t.Mask {
+		if strings.ContainsRune(builtinMaskCharacters, char) {
+			continue
+		}
+		if alphabets == nil {
+			alphabets = make(map[string]string)
+		}
+		alphabets[string(char)] = Alphabet(char)
+	}
+
+	return alphabets
+}
@@ -0,0 +1,129 @@
+package noid
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+)
+
+const crockfordUpper = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Forgets a registered mask character, as if in a fresh process
+func unregisterAlphabet(char rune) {
+	maskCharactersMu.Lock()
+	delete(maskCharacters, char)
+	maskCharactersMu.Unlock()
+}
+
+// Registers a mask character for the rest of the test only, so tests don't
+// leak alphabets into each other
+func registerTestAlphabet(char rune, alphabet string, t *testing.T) {
+	t.Helper()
+	if err := RegisterAlphabet(char, alphabet); err != nil {
+		t.Fatalf("Unable to register %q: %s", char, err)
+	}
+	t.Cleanup(func() { unregisterAlphabet(char) })
+}
+
+func TestRegisterAlphabetValidation(t *testing.T) {
+	var bad = map[rune]string{
+		'r': "01",               // Reserved
+		'k': "01",               // Reserved
+		'd': "01",               // Already registered
+		'5': "01",               // Not a letter
+		'q': "0",                // Too small
+		'x': "0123456789",       // Not a power of two
+		'w': "0123456701234567", // Duplicates
+	}
+
+	for char, alphabet := range bad {
+		if err := RegisterAlphabet(char, alphabet); err == nil {
+			t.Errorf("Expected registering %q as %#v to fail", char, alphabet)
+		}
+	}
+}
+
+func TestCustomAlphabets(t *testing.T) {
+	registerTestAlphabet('h', "0123456789abcdef", t)
+	registerTestAlphabet('C', crockfordUpper, t)
+
+	minter, err := NewMinter("x.shhhh")
+	if err != nil {
+		t.Fatalf("Unable to create hex minter: %s", err)
+	}
+	minter.HoldRange(0, 0xbee)
+	assertEqualS("x.0bef", minter.Mint(), "hex minting", t)
+
+	minter, _ = NewSequencedMinter("sCC", 1023)
+	assertEqualS("ZZ", minter.Mint(), "Crockford minting", t)
+
+	template, _ := NewTemplate("rCChhk")
+	minter, _ = NewTemplateMinter(template, 0)
+	for i := uint64(0); i < 300; i++ {
+		id := minter.Mint()
+		seq, err := template.Decode(id)
+		if err != nil {
+			t.Fatalf("Unable to decode %#v: %s", id, err)
+		}
+		assertEqualUint64(i, seq, "decoding "+id, t)
+	}
+
+	if err := template.Validate("zz00" + string(LegacyCheckDigit.Compute("zz00"))); err == nil {
+		t.Errorf("Expected lowercase to be invalid for an uppercase alphabet")
+	}
+}
+
+func TestUnregisteredMaskCharacters(t *testing.T) {
+	if _, err := NewTemplate("sjjj"); err == nil {
+		t.Errorf("Expected an unregistered mask character to be an error")
+	}
+}
+
+func TestCustomAlphabetsArePersisted(t *testing.T) {
+	var buf bytes.Buffer
+
+	registerTestAlphabet('b', "01", t)
+	minter, err := NewMinter("zbbb")
+	if err != nil {
+		t.Fatalf("Unable to create binary minter: %s", err)
+	}
+	for i := 0; i < 9; i++ {
+		minter.Mint()
+	}
+	assertEqualS("1001", minter.Mint(), "binary minting", t)
+	minter.WriteJSON(&buf)
+	jsonData := buf.Bytes()
+	binaryData, _ := minter.MarshalBinary()
+	var gobData bytes.Buffer
+	if err = gob.NewEncoder(&gobData).Encode(minter); err != nil {
+		t.Fatalf("Unable to gob-encode minter: %s", err)
+	}
+
+	// Fake a fresh process by forgetting the registration: decoding mustn't
+	// register the alphabet on its own
+	unregisterAlphabet('b')
+
+	if _, err = NewMinterFromJSON(bytes.NewReader(jsonData)); err == nil {
+		t.Errorf("Expected JSON with an unregistered alphabet to be rejected")
+	}
+	if err = (&Minter{}).UnmarshalBinary(binaryData); err == nil {
+		t.Errorf("Expected binary data with an unregistered alphabet to be rejected")
+	}
+	if err = gob.NewDecoder(bytes.NewReader(gobData.Bytes())).Decode(&Minter{}); err == nil {
+		t.Errorf("Expected gob data with an unregistered alphabet to be rejected")
+	}
+	assertEqualS("", Alphabet('b'), "alphabet after decoding", t)
+
+	registerTestAlphabet('b', "01", t)
+	minter, err = NewMinterFromJSON(bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Unable to read minter: %s", err)
+	}
+	assertEqualS("1010", minter.Mint(), "binary minting after a round trip", t)
+
+	unregisterAlphabet('b')
+	registerTestAlphabet('b', "10", t)
+	if _, err = NewMinterFromJSON(bytes.NewReader(jsonData)); err == nil {
+		t.Errorf("Expected JSON with a mismatched alphabet to be rejected")
+	}
+}
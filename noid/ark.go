@@ -0,0 +1,287 @@
+package noid
+
+// This file handles ARK identifiers: "ark:/" + NAAN + "/" + shoulder + noid,
+// optionally followed by a qualifier
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The characters the ARK and NOID specs allow in NAANs, shoulders, and noids
+const betanumerics = "0123456789bcdfghjkmnpqrstvwxz"
+
+const arkLabel = "ark:/"
+
+// ARK holds the parts of an ARK which come before a minted noid.  A template
+// with an ARK uses these in place of a prefix, and its check digit covers
+// the NAAN-qualified name ("12345/x5..."), as the ARK spec requires.
+//
+// Shoulders must follow the spec's "first digit convention": one or more
+// letters followed by a single digit (e.g., "x5" or "fk4").  This is what
+// lets ParseARK find the shoulder in an ARK from an unknown minter.  The
+// shoulder may also be empty.
+type ARK struct {
+	NAAN     string
+	Shoulder string
+}
+
+// ARKParts holds the pieces of a parsed ARK.  Noid is everything after the
+// shoulder, including any check digit, up to the qualifier.  Qualifier
+// includes its leading "/" or ".", if there is one.
+type ARKParts struct {
+	NAAN      string
+	Shoulder  string
+	Noid      string
+	Qualifier string
+}
+
+// NewARK returns an ARK profile for the given NAAN and shoulder
+func NewARK(naan, shoulder string) (*ARK, error) {
+	a := &ARK{NAAN: naan, Shoulder: shoulder}
+	if err := a.validate(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// NewARKTemplate returns a template minting ARKs under the given NAAN and
+// shoulder.  The template string must not have its own prefix, e.g.:
+//
+//	NewARKTemplate("12345", "x5", "reedeek")
+func NewARKTemplate(naan, shoulder, template string) (*Template, error) {
+	a, err := NewARK(naan, shoulder)
+	if err != nil {
+		return nil, err
+	}
+	t, err := NewTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+	if t.Prefix != "" || t.Separator != "" || t.ARK != nil {
+		return nil, errors.New("ARK templates cannot have a prefix")
+	}
+
+	t.ARK = a
+	return t, nil
+}
+
+// NewARKMinter returns a minter for NewARKTemplate's template
+func NewARKMinter(naan, shoulder, template string) (*Minter, error) {
+	t, err := NewARKTemplate(naan, shoulder, template)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMinter(t.String())
+}
+
+func isBetanumeric(s string) bool {
+	for _, char := range s {
+		if !strings.ContainsRune(betanumerics, char) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the length of the shoulder at the start of name according to the
+// first digit convention, or zero if name doesn't start with a shoulder
+func shoulderLength(name string) int {
+	for i, char := range name {
+		if char >= '0' && char <= '9' {
+			if i == 0 {
+				return 0
+			}
+			return i + 1
+		}
+		if !strings.ContainsRune(betanumerics, char) {
+			return 0
+		}
+	}
+
+	return 0
+}
+
+func (a *ARK) validate() error {
+	if a.NAAN == "" || !isBetanumeric(a.NAAN) {
+		return fmt.Errorf("NAAN %#v must be made up of digits and consonants", a.NAAN)
+	}
+	if a.Shoulder != "" && (!isBetanumeric(a.Shoulder) || shoulderLength(a.Shoulder) != len(a.Shoulder)) {
+		return fmt.Errorf("Shoulder %#v must be one or more consonants followed by a digit", a.Shoulder)
+	}
+	return nil
+}
+
+// String returns the ARK's label, NAAN, and shoulder, e.g., "ark:/12345/x5"
+func (a *ARK) String() string {
+	return arkLabel + a.NAAN + "/" + a.Shoulder
+}
+
+// Parses the head of an ARK template string, e.g., "ark:/12345/x5"
+func parseARKPrefix(head string) (*ARK, error) {
+	parts := strings.SplitN(strings.TrimPrefix(head, arkLabel), "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("ARK template %#v must have a NAAN followed by a slash", head)
+	}
+
+	a := &ARK{NAAN: parts[0], Shoulder: parts[1]}
+	if err := a.validate(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// NormalizeARK returns the normalized form of an ARK, following the ARK
+// spec's rules for lexical equivalence:
+//
+//   - The "ark:" label is lowercased, and "ark:" without a slash becomes
+//     "ark:/"
+//   - Hyphens are insignificant, so they're removed
+//   - The NAAN is lowercased; the rest of the ARK stays case-sensitive
+//   - Repeated slashes are collapsed, and a trailing "/" or "." is removed
+func NormalizeARK(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case hasPrefixFold(s, arkLabel):
+		s = s[len(arkLabel):]
+	case hasPrefixFold(s, "ark:"):
+		s = s[len("ark:"):]
+	default:
+		return "", fmt.Errorf("%#v doesn't start with %#v", s, arkLabel)
+	}
+
+	s = strings.ReplaceAll(s, "-", "")
+	for strings.Contains(s, "//") {
+		s = strings.ReplaceAll(s, "//", "/")
+	}
+	s = strings.TrimRight(s, "/.")
+
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", fmt.Errorf("%#v doesn't have a NAAN", s)
+	}
+
+	return arkLabel + strings.ToLower(parts[0]) + "/" + parts[1], nil
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// EquivalentARKs returns true if the two strings are valid ARKs that
+// normalize to the same thing
+func EquivalentARKs(a, b string) bool {
+	na, err := NormalizeARK(a)
+	if err != nil {
+		return false
+	}
+	nb, err := NormalizeARK(b)
+	if err != nil {
+		return false
+	}
+
+	return na == nb
+}
+
+// Builds the full ARK for a generated suffix
+func (a *ARK) format(suffix string, cd CheckDigit) string {
+	name := a.NAAN + "/" + a.Shoulder + suffix
+	if cd != nil {
+		name = name + string(cd.Compute(name))
+	}
+
+	return arkLabel + name
+}
+
+// Returns the suffix part of an ARK after verifying its NAAN, shoulder, and
+// check digit.  The ARK is normalized first, and any qualifier is ignored,
+// since a qualified ARK still refers to the same noid.
+func (a *ARK) suffixOf(id string, cd CheckDigit) (string, error) {
+	p, err := a.Parse(id)
+	if err != nil {
+		return "", err
+	}
+
+	suffix := p.Noid
+	if cd != nil {
+		runes := []rune(suffix)
+		if len(runes) < 2 {
+			return "", fmt.Errorf("%#v is too short to have a check digit", id)
+		}
+
+		last := len(runes) - 1
+		suffix = string(runes[:last])
+		if cd.Compute(p.NAAN+"/"+p.Shoulder+suffix) != runes[last] {
+			return "", fmt.Errorf("%#v has an incorrect check digit", id)
+		}
+	}
+
+	return suffix, nil
+}
+
+// Parse normalizes and splits an ARK into its parts, requiring the NAAN and
+// shoulder to match this profile
+func (a *ARK) Parse(s string) (*ARKParts, error) {
+	p, err := splitARK(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.NAAN != a.NAAN {
+		return nil, fmt.Errorf("%#v doesn't have NAAN %#v", s, a.NAAN)
+	}
+	if !strings.HasPrefix(p.Noid, a.Shoulder) {
+		return nil, fmt.Errorf("%#v doesn't have shoulder %#v", s, a.Shoulder)
+	}
+
+	p.Shoulder = a.Shoulder
+	p.Noid = p.Noid[len(a.Shoulder):]
+	return p, nil
+}
+
+// ParseARK normalizes and splits any ARK into its parts, using the first digit
+// convention to find its shoulder
+func ParseARK(s string) (*ARKParts, error) {
+	p, err := splitARK(s)
+	if err != nil {
+		return nil, err
+	}
+
+	n := shoulderLength(p.Noid)
+	p.Shoulder = p.Noid[:n]
+	p.Noid = p.Noid[n:]
+	return p, nil
+}
+
+// Normalizes an ARK and splits it into NAAN, name, and qualifier, leaving the
+// name in Noid
+func splitARK(s string) (*ARKParts, error) {
+	s, err := NormalizeARK(s)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.SplitN(s[len(arkLabel):], "/", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return nil, fmt.Errorf("%#v doesn't have a NAAN", s)
+	}
+
+	p := &ARKParts{NAAN: parts[0], Noid: parts[1]}
+	if i := strings.IndexAny(p.Noid, "/."); i != -1 {
+		p.Qualifier = p.Noid[i:]
+		p.Noid = p.Noid[:i]
+	}
+	if p.Noid == "" {
+		return nil, fmt.Errorf("%#v doesn't have a name", s)
+	}
+
+	return p, nil
+}
+
+// String puts the parts back together into a full ARK
+func (p *ARKParts) String() string {
+	return arkLabel + p.NAAN + "/" + p.Shoulder + p.Noid + p.Qualifier
+}
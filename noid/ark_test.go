@@ -0,0 +1,198 @@
+package noid
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestARKTemplate(t *testing.T) {
+	str := "ark:/12345/x5reedeek"
+	template, err := NewTemplate(str)
+	if err != nil {
+		t.Fatalf("Unable to parse %#v: %s", str, err)
+	}
+
+	assertTemplateAttributeS(str, "NAAN", "12345", template.ARK.NAAN, t)
+	assertTemplateAttributeS(str, "shoulder", "x5", template.ARK.Shoulder, t)
+	assertTemplateAttributeS(str, "prefix", "", template.Prefix, t)
+	assertTemplateAttributeS(str, "mask", "eedee", template.Mask, t)
+	assertTemplateAttributeB(str, "hasCheckDigit", true, template.HasCheckDigit, t)
+	assertTemplateAttributeS(str, "string", str, template.String(), t)
+
+	other, _ := NewARKTemplate("12345", "x5", "reedeek")
+	assertTemplateAttributeS(str, "string", str, other.String(), t)
+}
+
+func TestARKTemplateWithoutShoulder(t *testing.T) {
+	str := "ark:/12345/zdd"
+	template, err := NewTemplate(str)
+	if err != nil {
+		t.Fatalf("Unable to parse %#v: %s", str, err)
+	}
+	assertTemplateAttributeS(str, "shoulder", "", template.ARK.Shoulder, t)
+	assertTemplateAttributeS(str, "mask", "dd", template.Mask, t)
+}
+
+func TestBadARKs(t *testing.T) {
+	for _, s := range [][3]string{{"", "x5", "reedeek"}, {"12a45", "x5", "reedeek"}, {"12345", "x", "reedeek"}, {"12345", "5x", "reedeek"}, {"12345", "x5", "foo.reedeek"}} {
+		if _, err := NewARKTemplate(s[0], s[1], s[2]); err == nil {
+			t.Errorf("Expected %#v to be an invalid ARK template", s)
+		}
+	}
+}
+
+func TestMintingARKs(t *testing.T) {
+	arkMinter, _ := NewARKMinter("12345", "x5", "reedeek")
+	plainMinter, _ := NewMinter("reedee")
+
+	for i := 0; i < 100; i++ {
+		suffix := plainMinter.Mint()
+		name := "12345/x5" + suffix
+		expected := "ark:/" + name + string(computeCheckDigit(name))
+		assertEqualS(expected, arkMinter.Mint(), "ARK minting", t)
+	}
+}
+
+func TestARKCheckDigitIncludesNAAN(t *testing.T) {
+	a, _ := NewARKMinter("12345", "x5", "reedeek")
+	b, _ := NewARKMinter("54321", "x5", "reedeek")
+	idA, idB := a.Mint(), b.Mint()
+	if idA[len(idA)-1] == idB[len(idB)-1] {
+		t.Errorf("Expected different NAANs to give different check digits: %s, %s", idA, idB)
+	}
+}
+
+func TestARKValidateAndDecode(t *testing.T) {
+	minter, _ := NewARKMinter("12345", "x5", "reedeek")
+	template, _ := NewARKTemplate("12345", "x5", "reedeek")
+
+	for i := uint64(0); i < 100; i++ {
+		id := minter.Mint()
+		seq, err := template.Decode(id)
+		if err != nil {
+			t.Fatalf("Unable to decode %#v: %s", id, err)
+		}
+		assertEqualUint64(i, seq, "decoding "+id, t)
+	}
+
+	for _, id := range []string{"ark:/12345/x5q67j4", "ark:/54321/x5q67j4g", "ark:/12345/b5q67j4g", "ark:/12345/x5q67j4g/page2", "ark:/12345/x5Q67J4T"} {
+		if err := template.Validate(id); err == nil {
+			t.Errorf("Expected %#v to be invalid", id)
+		}
+	}
+}
+
+func TestParseARK(t *testing.T) {
+	var tests = map[string]ARKParts{
+		"ark:/12345/x5q67j4g":                {"12345", "x5", "q67j4g", ""},
+		"ark:/12345/x5q67j4g/page2.pdf":      {"12345", "x5", "q67j4g", "/page2.pdf"},
+		"ark:/12345/x5q67j4g.v2":             {"12345", "x5", "q67j4g", ".v2"},
+		"ark:/13030/tf5p30086":               {"13030", "tf5", "p30086", ""},
+		"ark:/13030/654xz321/s3/f8.05v.tiff": {"13030", "", "654xz321", "/s3/f8.05v.tiff"},
+	}
+
+	for s, expected := range tests {
+		p, err := ParseARK(s)
+		if err != nil {
+			t.Errorf("Unable to parse %#v: %s", s, err)
+			continue
+		}
+		if !reflect.DeepEqual(expected, *p) {
+			t.Errorf("Expected %#v to parse to %#v, got %#v", s, expected, *p)
+		}
+		assertEqualS(s, p.String(), "ARK parts round trip", t)
+	}
+
+	for _, s := range []string{"12345/x5q67j4g", "ark:/12345", "ark://x5q67j4g", "ark:/12345/"} {
+		if _, err := ParseARK(s); err == nil {
+			t.Errorf("Expected %#v not to parse", s)
+		}
+	}
+}
+
+func TestParseARKWithProfile(t *testing.T) {
+	a, _ := NewARK("12345", "x")
+	if a != nil {
+		t.Errorf("Expected shoulder without a digit to be invalid")
+	}
+
+	a, _ = NewARK("12345", "x5")
+	p, err := a.Parse("ark:/12345/x5q67j4g")
+	if err != nil {
+		t.Fatalf("Unable to parse: %s", err)
+	}
+	assertEqualS("q67j4g", p.Noid, "noid part", t)
+
+	if _, err = a.Parse("ark:/12345/b5q67j4g"); err == nil {
+		t.Errorf("Expected a different shoulder to fail parsing")
+	}
+}
+
+func TestNormalizeARK(t *testing.T) {
+	var tests = map[string]string{
+		"ark:/12345/x5q67j4t":         "ark:/12345/x5q67j4t",
+		"ARK:/12345/x5q67j4t":         "ark:/12345/x5q67j4t",
+		"ark:12345/x5q67j4t":          "ark:/12345/x5q67j4t",
+		"Ark:12345/x5-q67-j4t":        "ark:/12345/x5q67j4t",
+		"ark:/12BCD/x5q67j4t":         "ark:/12bcd/x5q67j4t",
+		"ark:/12345/x5Q67J4T":         "ark:/12345/x5Q67J4T",
+		" ark:/12345//x5q67j4t/ ":     "ark:/12345/x5q67j4t",
+		"ark:/12345/x5q67j4t/page2.":  "ark:/12345/x5q67j4t/page2",
+		"ark:/12345/x5q67j4t.v2/p-1/": "ark:/12345/x5q67j4t.v2/p1",
+	}
+
+	for raw, expected := range tests {
+		normalized, err := NormalizeARK(raw)
+		if err != nil {
+			t.Errorf("Unable to normalize %#v: %s", raw, err)
+			continue
+		}
+		assertEqualS(expected, normalized, "normalizing "+raw, t)
+	}
+
+	for _, raw := range []string{"12345/x5q67j4t", "ark", "ark:/", "ark:/12345", "urn:ark:/12345/x"} {
+		if _, err := NormalizeARK(raw); err == nil {
+			t.Errorf("Expected %#v not to normalize", raw)
+		}
+	}
+}
+
+func TestEquivalentARKs(t *testing.T) {
+	if !EquivalentARKs("ark:/12345/x5q67j4t", "ARK:12345/x5-q67j4t/") {
+		t.Errorf("Expected ARKs to be equivalent")
+	}
+	if EquivalentARKs("ark:/12345/x5q67j4t", "ark:/12345/x5q67j4t/page2") {
+		t.Errorf("Expected ARKs with different qualifiers not to be equivalent")
+	}
+	if EquivalentARKs("ark:/12345/x5q67j4t", "ark:/12345/X5Q67J4T") {
+		t.Errorf("Expected ARKs with different case after the NAAN not to be equivalent")
+	}
+	if EquivalentARKs("nope", "nope") {
+		t.Errorf("Expected invalid ARKs not to be equivalent")
+	}
+}
+
+func TestRawARKsDecodeLikeNormalizedARKs(t *testing.T) {
+	template, _ := NewARKTemplate("12bcd", "x5", "reedeek")
+	minter, _ := NewMinter(template.String())
+	minter.Mint()
+	id := minter.Mint()
+	raw := "ARK:12BCD/" + id[len("ark:/12bcd/"):len(id)-2] + "-" + id[len(id)-2:] + "/page2.pdf"
+
+	expected, _ := template.Decode(id)
+	seq, err := template.Decode(raw)
+	if err != nil {
+		t.Fatalf("Unable to decode %#v: %s", raw, err)
+	}
+	assertEqualUint64(expected, seq, "decoding "+raw, t)
+
+	canonical, _ := template.Canonical(raw)
+	assertEqualS(id, canonical, "canonical form of "+raw, t)
+
+	minter.SetStatus(raw, Public, "", time.Now())
+	l, _ := minter.Status(id)
+	if l.Status != Public {
+		t.Errorf("Expected setting status on %#v to affect %#v", raw, id)
+	}
+}
@@ -0,0 +1,499 @@
+package noid
+
+// This file handles a compact binary form of a minter's state, for embedding
+// minters in gob streams or anywhere JSON is too bulky
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Every binary minter starts with binaryMagic and a version byte.  Version 1
+// is the SerializeableMinter fields, in order, with integers as varints,
+// strings as a length and bytes, optional values behind a presence flag, and
+// map entries sorted by key so the same minter always encodes the same way.
+const (
+	binaryMagic   = "noid"
+	binaryVersion = 1
+)
+
+// MarshalBinary returns the minter's state in a compact, versioned binary
+// format
+func (m *Minter) MarshalBinary() ([]byte, error) {
+	sm := m.serializeable()
+	w := &binaryWriter{}
+	w.buf.WriteString(binaryMagic)
+	w.buf.WriteByte(binaryVersion)
+
+	w.string(sm.Template)
+	w.stringMap(sm.Alphabets)
+	w.string(sm.CheckDigit)
+	w.grouping(sm.Grouping)
+	w.optionalString(sm.Separator)
+	w.uint(sm.Sequence)
+	w.string(sm.Bucket)
+
+	w.uint(uint64(len(sm.Buckets)))
+	var buckets []string
+	for b := range sm.Buckets {
+		buckets = append(buckets, b)
+	}
+	for _, b := range sortedStrings(buckets) {
+		w.string(b)
+		w.uint(sm.Buckets[b].Sequence)
+		w.bool(sm.Buckets[b].Exhausted)
+		w.ranges(sm.Buckets[b].Skipped)
+	}
+
+	w.bool(sm.Exhausted)
+	w.ranges(sm.Holds)
+	w.ranges(sm.Skipped)
+
+	w.bool(sm.Blocklist != nil)
+	if sm.Blocklist != nil {
+		w.strings(sm.Blocklist.Substrings)
+		w.strings(sm.Blocklist.Patterns)
+	}
+
+	w.bool(sm.Shard != nil)
+	if sm.Shard != nil {
+		w.uint(sm.Shard.ID)
+		w.uint(sm.Shard.Count)
+	}
+
+	w.bool(sm.Lease != nil)
+	if sm.Lease != nil {
+		w.lease(*sm.Lease)
+	}
+	w.uint(uint64(len(sm.Leases)))
+	for _, l := range sm.Leases {
+		w.lease(l)
+	}
+	w.ranges(sm.Returned)
+
+	w.generations(sm.Generations)
+	w.templates(sm.Rollover)
+
+	w.uint(uint64(len(sm.Bindings)))
+	var bound []string
+	for id := range sm.Bindings {
+		bound = append(bound, id)
+	}
+	for _, id := range sortedStrings(bound) {
+		w.string(id)
+		w.stringMap(sm.Bindings[id])
+	}
+
+	w.uint(uint64(len(sm.Statuses)))
+	var statused []string
+	for id := range sm.Statuses {
+		statused = append(statused, id)
+	}
+	for _, id := range sortedStrings(statused) {
+		w.string(id)
+		w.lifecycle(sm.Statuses[id])
+	}
+
+	return w.buf.Bytes(), w.err
+}
+
+// UnmarshalBinary replaces the minter with the one in the given binary data,
+// validating it just as NewMinterFromJSON does.  The minter's clock is kept.
+func (m *Minter) UnmarshalBinary(data []byte) error {
+	if len(data) < len(binaryMagic)+1 || string(data[:len(binaryMagic)]) != binaryMagic {
+		return errors.New("Data isn't a binary minter")
+	}
+	if v := data[len(binaryMagic)]; v != binaryVersion {
+		return fmt.Errorf("Unsupported binary minter version %d", v)
+	}
+
+	r := &binaryReader{data: data[len(binaryMagic)+1:]}
+	var sm SerializeableMinter
+	sm.Template = r.string()
+	sm.Alphabets = r.stringMap()
+	sm.CheckDigit = r.string()
+	sm.Grouping = r.grouping()
+	sm.Separator = r.optionalString()
+	sm.Sequence = r.uint()
+	sm.Bucket = r.string()
+
+	for n := r.count(); n > 0; n-- {
+		if sm.Buckets == nil {
+			sm.Buckets = make(map[string]BucketState)
+		}
+		b := r.string()
+		sm.Buckets[b] = BucketState{Sequence: r.uint(), Exhausted: r.bool(), Skipped: r.ranges()}
+	}
+
+	sm.Exhausted = r.bool()
+	sm.Holds = r.ranges()
+	sm.Skipped = r.ranges()
+
+	if r.bool() {
+		sm.Blocklist = &Blocklist{Substrings: r.strings(), Patterns: r.strings()}
+	}
+
+	if r.bool() {
+		sm.Shard = &Shard{ID: r.uint(), Count: r.uint()}
+	}
+
+	if r.bool() {
+		l := r.lease()
+		sm.Lease = &l
+	}
+	for n := r.count(); n > 0; n-- {
+		sm.Leases = append(sm.Leases, r.lease())
+	}
+	sm.Returned = r.ranges()
+
+	sm.Generations = r.generations()
+	sm.Rollover = r.templates()
+
+	for n := r.count(); n > 0; n-- {
+		if sm.Bindings == nil {
+			sm.Bindings = make(map[string]Bindings)
+		}
+		id := r.string()
+		sm.Bindings[id] = r.stringMap()
+	}
+
+	for n := r.count(); n > 0; n-- {
+		if sm.Statuses == nil {
+			sm.Statuses = make(map[string]*Lifecycle)
+		}
+		id := r.string()
+		sm.Statuses[id] = r.lifecycle()
+	}
+
+	if r.err == nil && len(r.data) > 0 {
+		r.err = errors.New("Binary minter has trailing data")
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	return m.replaceWith(sm)
+}
+
+// Builds a minter from the serialized data and puts it in place of m, keeping
+// m's clock
+func (m *Minter) replaceWith(sm SerializeableMinter) error {
+	built, err := sm.minter()
+	if err != nil {
+		return err
+	}
+
+	built.clock = m.clock
+	*m = *built
+	return nil
+}
+
+func sortedStrings(keys []string) []string {
+	sort.Strings(keys)
+	return keys
+}
+
+type binaryWriter struct {
+	buf bytes.Buffer
+	err error
+}
+
+func (w *binaryWriter) uint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (w *binaryWriter) int(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+func (w *binaryWriter) bool(v bool) {
+	if v {
+		w.buf.WriteByte(1)
+	} else {
+		w.buf.WriteByte(0)
+	}
+}
+
+func (w *binaryWriter) string(s string) {
+	w.uint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *binaryWriter) strings(list []string) {
+	w.uint(uint64(len(list)))
+	for _, s := range list {
+		w.string(s)
+	}
+}
+
+func (w *binaryWriter) stringMap(m map[string]string) {
+	w.uint(uint64(len(m)))
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	for _, k := range sortedStrings(keys) {
+		w.string(k)
+		w.string(m[k])
+	}
+}
+
+func (w *binaryWriter) time(t time.Time) {
+	data, err := t.MarshalBinary()
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	w.string(string(data))
+}
+
+func (w *binaryWriter) ranges(ranges []SequenceRange) {
+	w.uint(uint64(len(ranges)))
+	for _, r := range ranges {
+		w.uint(r.Start)
+		w.uint(r.End)
+	}
+}
+
+func (w *binaryWriter) grouping(g *Grouping) {
+	w.bool(g != nil)
+	if g != nil {
+		w.int(int64(g.Size))
+		w.string(g.Separator)
+		w.bool(g.FromRight)
+	}
+}
+
+func (w *binaryWriter) template(st StoredTemplate) {
+	w.string(st.Template)
+	w.string(st.CheckDigit)
+	w.grouping(st.Grouping)
+	w.optionalString(st.Separator)
+}
+
+func (w *binaryWriter) optionalString(s *string) {
+	w.bool(s != nil)
+	if s != nil {
+		w.string(*s)
+	}
+}
+
+func (w *binaryWriter) templates(templates []StoredTemplate) {
+	w.uint(uint64(len(templates)))
+	for _, st := range templates {
+		w.template(st)
+	}
+}
+
+func (w *binaryWriter) generations(generations []StoredGeneration) {
+	w.uint(uint64(len(generations)))
+	for _, sg := range generations {
+		w.template(sg.StoredTemplate)
+		w.ranges(sg.Holds)
+		w.ranges(sg.Returned)
+		w.ranges(sg.Skipped)
+	}
+}
+
+func (w *binaryWriter) lease(l Lease) {
+	w.string(l.Client)
+	w.uint(l.Start)
+	w.uint(l.End)
+	w.time(l.Expires)
+	w.int(int64(l.Generation))
+	w.bool(l.Closed)
+}
+
+func (w *binaryWriter) lifecycle(l *Lifecycle) {
+	w.int(int64(l.Status))
+	w.string(l.Reason)
+	w.bool(l.Tombstone != nil)
+	if l.Tombstone != nil {
+		w.time(*l.Tombstone)
+	}
+	w.uint(uint64(len(l.History)))
+	for _, c := range l.History {
+		w.int(int64(c.From))
+		w.int(int64(c.To))
+		w.time(c.Time)
+		w.string(c.Reason)
+	}
+}
+
+// Reads what binaryWriter writes.  The first error stops all further reads,
+// which return zero values, so callers only need to check err at the end.
+type binaryReader struct {
+	data []byte
+	err  error
+}
+
+func (r *binaryReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+	r.data = nil
+}
+
+func (r *binaryReader) uint() uint64 {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail(errors.New("Binary minter is truncated or corrupt"))
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *binaryReader) int() int64 {
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.fail(errors.New("Binary minter is truncated or corrupt"))
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+// Reads a length or count, making sure it's no more than the bytes left, so
+// corrupt data can't trigger a huge allocation
+func (r *binaryReader) count() uint64 {
+	n := r.uint()
+	if n > uint64(len(r.data)) {
+		r.fail(errors.New("Binary minter is truncated or corrupt"))
+		return 0
+	}
+	return n
+}
+
+func (r *binaryReader) bool() bool {
+	if len(r.data) == 0 {
+		r.fail(errors.New("Binary minter is truncated or corrupt"))
+		return false
+	}
+	v := r.data[0]
+	r.data = r.data[1:]
+	if v > 1 {
+		r.fail(errors.New("Binary minter is truncated or corrupt"))
+	}
+	return v == 1
+}
+
+func (r *binaryReader) string() string {
+	n := r.count()
+	s := string(r.data[:n])
+	r.data = r.data[n:]
+	return s
+}
+
+func (r *binaryReader) strings() []string {
+	var list []string
+	for n := r.count(); n > 0; n-- {
+		list = append(list, r.string())
+	}
+	return list
+}
+
+func (r *binaryReader) stringMap() map[string]string {
+	n := r.count()
+	if n == 0 {
+		return nil
+	}
+	m := make(map[string]string)
+	for ; n > 0; n-- {
+		k := r.string()
+		m[k] = r.string()
+	}
+	return m
+}
+
+func (r *binaryReader) time() time.Time {
+	var t time.Time
+	if err := t.UnmarshalBinary([]byte(r.string())); err != nil && r.err == nil {
+		r.fail(err)
+	}
+	return t
+}
+
+func (r *binaryReader) ranges() []SequenceRange {
+	var ranges []SequenceRange
+	for n := r.count(); n > 0; n-- {
+		ranges = append(ranges, SequenceRange{Start: r.uint(), End: r.uint()})
+	}
+	return ranges
+}
+
+func (r *binaryReader) grouping() *Grouping {
+	if !r.bool() {
+		return nil
+	}
+	return &Grouping{Size: int(r.int()), Separator: r.string(), FromRight: r.bool()}
+}
+
+func (r *binaryReader) template() StoredTemplate {
+	return StoredTemplate{Template: r.string(), CheckDigit: r.string(), Grouping: r.grouping(), Separator: r.optionalString()}
+}
+
+func (r *binaryReader) optionalString() *string {
+	if !r.bool() {
+		return nil
+	}
+	s := r.string()
+	return &s
+}
+
+func (r *binaryReader) templates() []StoredTemplate {
+	var templates []StoredTemplate
+	for n := r.count(); n > 0; n-- {
+		templates = append(templates, r.template())
+	}
+	return templates
+}
+
+func (r *binaryReader) generations() []StoredGeneration {
+	var generations []StoredGeneration
+	for n := r.count(); n > 0; n-- {
+		generations = append(generations, StoredGeneration{StoredTemplate: r.template(), Holds: r.ranges(), Returned: r.ranges(), Skipped: r.ranges()})
+	}
+	return generations
+}
+
+func (r *binaryReader) lease() Lease {
+	return Lease{
+		Client:     r.string(),
+		Start:      r.uint(),
+		End:        r.uint(),
+		Expires:    r.time(),
+		Generation: int(r.int()),
+		Closed:     r.bool(),
+	}
+}
+
+func (r *binaryReader) status() Status {
+	s := Status(r.int())
+	if _, ok := statusNames[s]; !ok {
+		r.fail(fmt.Errorf("Unknown status %d", int(s)))
+	}
+	return s
+}
+
+func (r *binaryReader) lifecycle() *Lifecycle {
+	l := &Lifecycle{Status: r.status(), Reason: r.string()}
+	if r.bool() {
+		t := r.time()
+		l.Tombstone = &t
+	}
+	for n := r.count(); n > 0; n-- {
+		l.History = append(l.History, StatusChange{
+			From:   r.status(),
+			To:     r.status(),
+			Time:   r.time(),
+			Reason: r.string(),
+		})
+	}
+	return l
+}
@@ -0,0 +1,199 @@
+package noid
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// Builds a minter with a bit of everything in its state
+func busyMinter(t *testing.T) *Minter {
+	template, _ := NewTemplate("x.reedeek")
+	template.CheckDigit = NCDA
+	template.GroupSize = 3
+	m, _ := NewShardedMinter(template, 1, 2)
+	m.SetRollover(mustTemplate("x.reeedeek", t))
+	m.Block("xyz")
+	m.BlockPattern("^0+")
+
+	ids := m.Peek(5)
+	m.Hold(ids[4])
+	for i := 0; i < 3; i++ {
+		m.MintAndBind(Bindings{"n": string(rune('a' + i))})
+	}
+	m.SetStatus(ids[0], Withdrawn, "duplicate", time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC))
+	m.GrantLease("laptop", 10, time.Now().Add(time.Hour))
+
+	return m
+}
+
+func assertSameMinter(a, b *Minter, t *testing.T) {
+	var ja, jb bytes.Buffer
+	a.WriteJSON(&ja)
+	b.WriteJSON(&jb)
+	assertEqualS(ja.String(), jb.String(), "minter state", t)
+	assertEqualS(a.Mint(), b.Mint(), "next noid", t)
+}
+
+func TestMinterBinaryRoundTrip(t *testing.T) {
+	m := busyMinter(t)
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unable to marshal: %s", err)
+	}
+
+	var text bytes.Buffer
+	m.WriteJSON(&text)
+	if len(data) >= text.Len() {
+		t.Errorf("Expected binary (%d bytes) to be smaller than JSON (%d bytes)", len(data), text.Len())
+	}
+
+	again, _ := m.MarshalBinary()
+	if !bytes.Equal(data, again) {
+		t.Errorf("Expected the same minter to encode the same way every time")
+	}
+
+	m2 := &Minter{}
+	if err = m2.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unable to unmarshal: %s", err)
+	}
+	assertSameMinter(m, m2, t)
+}
+
+func TestMinterBinaryRejectsBadData(t *testing.T) {
+	m, _ := NewMinter("reedeek")
+	data, _ := m.MarshalBinary()
+
+	var tests = map[string][]byte{
+		"wrong magic":   append([]byte("nope"), data[4:]...),
+		"wrong version": append(append([]byte("noid"), 99), data[5:]...),
+		"truncated":     data[:len(data)-1],
+		"trailing data": append(append([]byte(nil), data...), 0),
+		"empty":         nil,
+	}
+	for name, bad := range tests {
+		if err := (&Minter{}).UnmarshalBinary(bad); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestMinterGob(t *testing.T) {
+	type state struct {
+		Name   string
+		Minter *Minter
+	}
+
+	m := busyMinter(t)
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(state{"photos", m}); err != nil {
+		t.Fatalf("Unable to encode: %s", err)
+	}
+
+	var s state
+	if err := gob.NewDecoder(&buf).Decode(&s); err != nil {
+		t.Fatalf("Unable to decode: %s", err)
+	}
+	assertEqualS("photos", s.Name, "name", t)
+	assertSameMinter(m, s.Minter, t)
+}
+
+func TestMinterEmbeddedJSON(t *testing.T) {
+	type config struct {
+		Name   string
+		Minter *Minter
+	}
+
+	m := busyMinter(t)
+	data, err := json.Marshal(config{"photos", m})
+	if err != nil {
+		t.Fatalf("Unable to marshal: %s", err)
+	}
+
+	var c config
+	if err = json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("Unable to unmarshal: %s", err)
+	}
+	assertSameMinter(m, c.Minter, t)
+
+	if err = json.Unmarshal([]byte(`{"Minter":{"Template":"x.q"}}`), &c); err == nil {
+		t.Errorf("Expected an error for an invalid template")
+	}
+}
+
+func TestTemplateText(t *testing.T) {
+	plain := mustTemplate("x.reedeek", t)
+	text, _ := plain.MarshalText()
+	assertEqualS("x.reedeek", string(text), "plain template text", t)
+
+	grouped := mustTemplate("x.rddddk", t)
+	grouped.CheckDigit = Damm
+	grouped.GroupSize = 4
+	grouped.GroupSeparator = "_"
+	grouped.GroupFromRight = true
+	text, _ = grouped.MarshalText()
+	assertEqualS("x.rddddk?check-digit=damm&group=4&group-from=right&group-separator=_", string(text), "template text", t)
+
+	var parsed Template
+	if err := parsed.UnmarshalText(text); err != nil {
+		t.Fatalf("Unable to unmarshal %#v: %s", string(text), err)
+	}
+	if parsed != *grouped {
+		t.Errorf("Expected %#v, got %#v", grouped, parsed)
+	}
+
+	// A "?" in the prefix isn't mistaken for settings
+	if err := parsed.UnmarshalText([]byte("what?reedeek")); err != nil {
+		t.Fatalf("Unable to unmarshal a prefix with a question mark: %s", err)
+	}
+	assertEqualS("what?", parsed.Prefix, "prefix with a question mark", t)
+
+	for _, bad := range []string{"x.reedeek?group=-1", "x.reedeek?check-digit=nope", "x.reedeek?group=2&group-from=up", "x.q"} {
+		if err := parsed.UnmarshalText([]byte(bad)); err == nil {
+			t.Errorf("Expected an error unmarshaling %#v", bad)
+		}
+	}
+}
+
+func TestTemplateTextCarriesAlphabets(t *testing.T) {
+	registerTestAlphabet('h', "0123456789abcdef", t)
+
+	template := mustTemplate("x.rhhh", t)
+	data, _ := json.Marshal(template)
+	assertEqualS(`"x.rhhh?alphabet=h%3A0123456789abcdef"`, string(data), "template JSON", t)
+
+	// Fake a fresh process by forgetting the registration: the text alone
+	// mustn't register anything
+	unregisterAlphabet('h')
+
+	var parsed Template
+	if err := json.Unmarshal(data, &parsed); err == nil {
+		t.Errorf("Expected an unregistered alphabet to be rejected")
+	}
+	assertEqualS("", Alphabet('h'), "alphabet after rejected text", t)
+
+	registerTestAlphabet('h', "0123456789abcdef", t)
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("Unable to unmarshal: %s", err)
+	}
+	assertEqualS("x.rhhh", parsed.String(), "template with a registered alphabet", t)
+
+	unregisterAlphabet('h')
+	registerTestAlphabet('h', "fedcba9876543210", t)
+	if err := json.Unmarshal(data, &parsed); err == nil {
+		t.Errorf("Expected a mismatched alphabet to be rejected")
+	}
+}
+
+func TestTemplateTextSortsAlphabets(t *testing.T) {
+	registerTestAlphabet('w', "0123456789abcdef", t)
+	registerTestAlphabet('j', "0123", t)
+
+	template := mustTemplate("x.rjjww", t)
+	for i := 0; i < 20; i++ {
+		text, _ := template.MarshalText()
+		assertEqualS("x.rjjww?alphabet=j%3A0123&alphabet=w%3A0123456789abcdef", string(text), "template text with two alphabets", t)
+	}
+}
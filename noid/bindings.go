@@ -0,0 +1,94 @@
+package noid
+
+// This file handles the metadata ("bindings") attached to minted noids
+
+import "errors"
+
+// Bindings are simple key/value pairs stored alongside a minted noid
+type Bindings map[string]string
+
+// Returns a copy of the bindings so callers can't alter a minter's data by
+// holding onto a map
+func (b Bindings) clone() Bindings {
+	c := make(Bindings, len(b))
+	for k, v := range b {
+		c[k] = v
+	}
+	return c
+}
+
+func (b Bindings) validate() error {
+	for k := range b {
+		if k == "" {
+			return errors.New("Binding keys cannot be empty")
+		}
+	}
+	return nil
+}
+
+// Bind sets the given key/value pairs on a noid, overwriting any existing
+// values for the same keys.  The noid must be valid for the minter's template.
+func (m *Minter) Bind(id string, b Bindings) error {
+	if err := b.validate(); err != nil {
+		return err
+	}
+
+	id, err := m.Canonical(id)
+	if err != nil {
+		return err
+	}
+
+	if m.bindings == nil {
+		m.bindings = make(map[string]Bindings)
+	}
+	if m.bindings[id] == nil {
+		m.bindings[id] = make(Bindings)
+	}
+	for k, v := range b {
+		m.bindings[id][k] = v
+	}
+
+	return nil
+}
+
+// Bindings returns a copy of all data bound to the given noid, or nil if
+// nothing has been bound
+func (m *Minter) Bindings(id string) Bindings {
+	id, err := m.Canonical(id)
+	if err != nil {
+		return nil
+	}
+
+	b := m.bindings[id]
+	if b == nil {
+		return nil
+	}
+	return b.clone()
+}
+
+// MintAndBind mints the next noid and binds the given data to it.  Nothing
+// is minted if the bindings are invalid.
+//
+// This only alters the in-memory minter; use Store.MintAndBind to make the
+// operation durable.
+func (m *Minter) MintAndBind(b Bindings) (string, error) {
+	if err := b.validate(); err != nil {
+		return "", err
+	}
+
+	if err := m.leaseError(); err != nil {
+		return "", err
+	}
+
+	id := m.Mint()
+	if id == "" && !m.Exhausted() {
+		return "", ErrBlockedRun
+	}
+	if id == "" {
+		return "", ErrExhausted
+	}
+	if len(b) > 0 {
+		m.Bind(id, b)
+	}
+	return id, nil
+}
@@ -0,0 +1,242 @@
+package noid
+
+// This file handles keeping noids with unwanted words out of circulation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A Blocklist holds substrings and regular expressions which a minter's noids
+// must never contain.  Only the part of a noid after its prefix (or ARK
+// shoulder) is checked, including any check digit, and without group
+// separators.  Substrings are matched ignoring case; patterns are matched as
+// written, so use "(?i)" for case-insensitive patterns.
+type Blocklist struct {
+	Substrings []string `json:",omitempty"`
+	Patterns   []string `json:",omitempty"`
+	compiled   []*regexp.Regexp
+}
+
+func (b *Blocklist) empty() bool {
+	return len(b.Substrings) == 0 && len(b.Patterns) == 0
+}
+
+func (b *Blocklist) clone() *Blocklist {
+	return &Blocklist{
+		Substrings: append([]string(nil), b.Substrings...),
+		Patterns:   append([]string(nil), b.Patterns...),
+		compiled:   append([]*regexp.Regexp(nil), b.compiled...),
+	}
+}
+
+// Returns true if the noid contains anything on the blocklist
+func (b *Blocklist) blocks(t *Template, id string) bool {
+	if b.empty() {
+		return false
+	}
+
+	body := t.body(id)
+	lower := strings.ToLower(body)
+	for _, s := range b.Substrings {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	for _, re := range b.compiled {
+		if re.MatchString(body) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *Blocklist) addSubstrings(substrings ...string) error {
+	for _, s := range substrings {
+		if s == "" {
+			return errors.New("Blocked substrings cannot be empty")
+		}
+	}
+
+	for _, s := range substrings {
+		s = strings.ToLower(s)
+		if !containsString(b.Substrings, s) {
+			b.Substrings = append(b.Substrings, s)
+		}
+	}
+	return nil
+}
+
+func (b *Blocklist) addPatterns(patterns ...string) error {
+	var compiled []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("Invalid blocklist pattern %#v: %s", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+
+	for i, p := range patterns {
+		if !containsString(b.Patterns, p) {
+			b.Patterns = append(b.Patterns, p)
+			b.compiled = append(b.compiled, compiled[i])
+		}
+	}
+	return nil
+}
+
+// blocklistSample is how many noids Block and BlockPattern check to make sure
+// a new blocklist leaves something to mint
+const blocklistSample = 1000
+
+// Switches to the given blocklist unless a sample of the template shows it
+// blocking every noid, which would leave Mint nothing to return
+func (m *Minter) setBlocklist(b *Blocklist) error {
+	impact := m.blocklistImpact(b, blocklistSample)
+	if impact.Checked > 0 && impact.Blocked == impact.Checked {
+		return fmt.Errorf("Blocklist would block all %d noids checked for template %#v", impact.Checked, m.template.String())
+	}
+	m.blocklist = *b
+	return nil
+}
+
+// Block adds substrings to the minter's blocklist.  Mint skips any noid
+// containing one, moving on to the next sequence value, so the same minter
+// state always mints the same noids.  Noids minted before they were blocked
+// still count as minted.  An error is returned, and nothing is added, if a
+// sample of the template's noids shows every one of them blocked.
+func (m *Minter) Block(substrings ...string) error {
+	b := m.blocklist.clone()
+	if err := b.addSubstrings(substrings...); err != nil {
+		return err
+	}
+	return m.setBlocklist(b)
+}
+
+// BlockPattern adds regular expressions to the minter's blocklist.  As with
+// Block, patterns which block every sampled noid are rejected.
+func (m *Minter) BlockPattern(patterns ...string) error {
+	b := m.blocklist.clone()
+	if err := b.addPatterns(patterns...); err != nil {
+		return err
+	}
+	return m.setBlocklist(b)
+}
+
+// Unblock removes a substring or pattern from the minter's blocklist.
+// Noids it blocked before won't be minted after all unless the minter hasn't
+// reached them yet, so they still don't count as minted.
+//
+// The lists are rebuilt rather than edited in place, since copies of the
+// minter, such as the one Peek uses, share them.
+func (m *Minter) Unblock(entry string) error {
+	b := &m.blocklist
+	for i, s := range b.Substrings {
+		if s == strings.ToLower(entry) {
+			b.Substrings = append(append([]string(nil), b.Substrings[:i]...), b.Substrings[i+1:]...)
+			return nil
+		}
+	}
+	for i, p := range b.Patterns {
+		if p == entry {
+			b.Patterns = append(append([]string(nil), b.Patterns[:i]...), b.Patterns[i+1:]...)
+			b.compiled = append(append([]*regexp.Regexp(nil), b.compiled[:i]...), b.compiled[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%#v isn't on the blocklist", entry)
+}
+
+// Blocklist returns a copy of the minter's blocklist
+func (m *Minter) Blocklist() *Blocklist {
+	return m.blocklist.clone()
+}
+
+// IsBlocked returns true if the given noid is valid for the minter's template
+// but contains something on its blocklist
+func (m *Minter) IsBlocked(id string) (bool, error) {
+	id, err := m.template.Canonical(id)
+	if err != nil {
+		return false, err
+	}
+	return m.blocklist.blocks(m.template, id), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// BlocklistImpact describes how much of a template's space a blocklist
+// removes.  When Exhaustive is false, only Checked evenly-spaced sequence
+// values were looked at, and Estimate extrapolates to the whole space.
+//
+// Total can't count past the largest uint64, so for templates with a full
+// 64 bits of sequence values (2^64 noids), it's capped at 2^64-1.
+type BlocklistImpact struct {
+	Total      uint64
+	Checked    uint64
+	Blocked    uint64
+	Exhaustive bool
+}
+
+// Estimate returns the number of noids the blocklist is expected to remove
+// from the template's entire space
+func (i BlocklistImpact) Estimate() uint64 {
+	if i.Exhaustive || i.Checked == 0 {
+		return i.Blocked
+	}
+	return uint64(float64(i.Blocked) / float64(i.Checked) * float64(i.Total))
+}
+
+// BlocklistImpact checks up to maxChecks noids from the minter's template
+// against its blocklist.  Templates with no more than maxChecks noids are
+// checked in full; otherwise, sequence values are sampled evenly across the
+// template's range.  Holds and the minter's current sequence are ignored.
+// Unlimited templates are only sampled within their 64-bit range.
+func (m *Minter) BlocklistImpact(maxChecks uint64) BlocklistImpact {
+	return m.blocklistImpact(&m.blocklist, maxChecks)
+}
+
+func (m *Minter) blocklistImpact(b *Blocklist, maxChecks uint64) BlocklistImpact {
+	max := m.generator.maxSequence
+	impact := BlocklistImpact{Total: max + 1}
+	if max == ^uint64(0) {
+		impact.Total = max
+	}
+	if maxChecks == 0 {
+		return impact
+	}
+
+	step := uint64(1)
+	if max >= maxChecks {
+		step = max/maxChecks + 1
+	} else {
+		impact.Exhaustive = true
+	}
+
+	t := m.template.forBucket(m.template.BucketFor(m.now()))
+	g := *m.generator
+	for seq := uint64(0); impact.Checked < maxChecks; seq += step {
+		g.sequenceValue = seq
+		if b.blocks(t, t.format(g.ToString())) {
+			impact.Blocked++
+		}
+		impact.Checked++
+
+		if max-seq < step {
+			break
+		}
+	}
+
+	return impact
+}
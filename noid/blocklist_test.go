@@ -0,0 +1,223 @@
+package noid
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBlocklistSkipsNoids(t *testing.T) {
+	plain, _ := NewMinter("x.seek")
+	blocked, _ := NewMinter("x.seek")
+	if err := blocked.Block("B"); err != nil {
+		t.Fatalf("Unable to block: %s", err)
+	}
+	if err := blocked.BlockPattern("^0"); err != nil {
+		t.Fatalf("Unable to block pattern: %s", err)
+	}
+
+	var expected []string
+	for !plain.Exhausted() {
+		id := plain.Mint()
+		body := strings.TrimPrefix(id, "x.")
+		if !strings.Contains(body, "b") && !strings.HasPrefix(body, "0") {
+			expected = append(expected, id)
+		}
+	}
+
+	for _, id := range expected {
+		assertEqualS(id, blocked.Mint(), "minting with a blocklist", t)
+	}
+	assertEqualS("", blocked.Mint(), "exhausted minter", t)
+	if !blocked.Exhausted() {
+		t.Errorf("Expected minter to be exhausted")
+	}
+}
+
+func TestBlocklistIgnoresPrefixAndGroups(t *testing.T) {
+	template, _ := NewTemplate("bad.reeeee")
+	template.GroupSize = 2
+	m, _ := NewTemplateMinter(template, 0)
+	m.Block("bad", "xyz")
+
+	for i := 0; i < 1000; i++ {
+		id := m.Mint()
+		if id == "" {
+			t.Fatalf("Expected the prefix not to block everything")
+		}
+		if body := template.body(id); strings.Contains(body, "bad") || strings.Contains(body, "xyz") {
+			t.Fatalf("Minted blocked noid %#v", id)
+		}
+	}
+}
+
+func TestBlockedNoidsAreNotMinted(t *testing.T) {
+	m, _ := NewMinter("sdd")
+	m.Block("1")
+	for i := 0; i < 20; i++ {
+		m.Mint()
+	}
+
+	for _, tc := range []struct {
+		id              string
+		minted, blocked bool
+	}{{"00", true, false}, {"01", false, true}, {"10", false, true}, {"23", true, false}} {
+		minted, _ := m.Minted(tc.id)
+		blocked, _ := m.IsBlocked(tc.id)
+		if minted != tc.minted || blocked != tc.blocked {
+			t.Errorf("Expected %#v to have minted %v and blocked %v, got %v and %v", tc.id, tc.minted, tc.blocked, minted, blocked)
+		}
+	}
+}
+
+func TestBlocklistChangesKeepMintedHistory(t *testing.T) {
+	m, _ := NewMinter("sdd")
+	m.Block("1")
+	for i := 0; i < 20; i++ {
+		m.Mint()
+	}
+	m.Unblock("1")
+	m.Block("2")
+
+	var buf bytes.Buffer
+	if err := m.WriteJSON(&buf); err != nil {
+		t.Fatalf("Unable to write minter: %s", err)
+	}
+	fromJSON, err := NewMinterFromJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unable to read minter: %s", err)
+	}
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unable to marshal minter: %s", err)
+	}
+	fromBinary := &Minter{}
+	if err = fromBinary.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unable to unmarshal minter: %s", err)
+	}
+
+	for _, minter := range []*Minter{m, fromJSON, fromBinary} {
+		for _, tc := range []struct {
+			id     string
+			minted bool
+		}{{"00", true}, {"01", false}, {"10", false}, {"23", true}, {"25", true}, {"37", false}} {
+			minted, _ := minter.Minted(tc.id)
+			if minted != tc.minted {
+				t.Errorf("Expected %#v to have minted %v after changing the blocklist, got %v", tc.id, tc.minted, minted)
+			}
+		}
+	}
+}
+
+func TestBlocklistCantBlockEverything(t *testing.T) {
+	m, _ := NewMinter("zd")
+	if err := m.BlockPattern("."); err == nil {
+		t.Errorf("Expected a pattern blocking every noid to be rejected")
+	}
+	if err := m.Block("0", "1", "2", "3", "4", "5", "6", "7"); err == nil {
+		t.Errorf("Expected substrings blocking every noid to be rejected")
+	}
+	if !m.Blocklist().empty() {
+		t.Errorf("Expected rejected blocks to leave the blocklist alone")
+	}
+	assertEqualS("0", m.Mint(), "minting after rejected blocks", t)
+
+	// A blocklist which gets past the sample can still block a long run, so
+	// Mint gives up rather than spinning
+	m.blocklist.addPatterns(".")
+	assertEqualS("", m.Mint(), "minting with everything blocked", t)
+	if m.Exhausted() {
+		t.Errorf("Expected a blocked run not to exhaust the minter")
+	}
+	if _, err := m.MintAndBind(Bindings{}); err != ErrBlockedRun {
+		t.Errorf("Expected ErrBlockedRun, got %v", err)
+	}
+}
+
+func TestBlocklistErrors(t *testing.T) {
+	m, _ := NewMinter("sdd")
+	if err := m.Block("a", ""); err == nil {
+		t.Errorf("Expected an empty substring to be rejected")
+	}
+	if err := m.BlockPattern("("); err == nil {
+		t.Errorf("Expected an invalid pattern to be rejected")
+	}
+	if len(m.Blocklist().Substrings) != 0 {
+		t.Errorf("Expected failed blocks to leave the blocklist alone")
+	}
+	if err := m.Unblock("nope"); err == nil {
+		t.Errorf("Expected unblocking a missing entry to fail")
+	}
+
+	m.Block("1")
+	m.BlockPattern("7$")
+	if err := m.Unblock("7$"); err != nil {
+		t.Errorf("Unable to unblock pattern: %s", err)
+	}
+	if err := m.Unblock("1"); err != nil {
+		t.Errorf("Unable to unblock substring: %s", err)
+	}
+	assertEqualS("00 01", strings.Join(m.Peek(2), " "), "minting after unblocking", t)
+}
+
+func TestUnblockLeavesCopiesAlone(t *testing.T) {
+	m, _ := NewMinter("sdd")
+	m.Block("1", "2")
+	m.BlockPattern("3$", "4$")
+	copied := *m
+
+	m.Unblock("1")
+	m.Unblock("3$")
+	assertEqualS("1 2", strings.Join(copied.blocklist.Substrings, " "), "copy's substrings after unblocking", t)
+	assertEqualS("3$ 4$", strings.Join(copied.blocklist.Patterns, " "), "copy's patterns after unblocking", t)
+	assertEqualS("3$", copied.blocklist.compiled[0].String(), "copy's first compiled pattern", t)
+	assertEqualS("2", strings.Join(m.blocklist.Substrings, " "), "substrings after unblocking", t)
+	assertEqualS("4$", strings.Join(m.blocklist.Patterns, " "), "patterns after unblocking", t)
+}
+
+func TestBlocklistImpact(t *testing.T) {
+	m, _ := NewMinter("sdd")
+	m.Block("7")
+
+	impact := m.BlocklistImpact(100)
+	if !impact.Exhaustive {
+		t.Errorf("Expected a 64-noid template to be checked in full")
+	}
+	assertEqualUint64(64, impact.Total, "total noids", t)
+	assertEqualUint64(64, impact.Checked, "checked noids", t)
+	assertEqualUint64(15, impact.Blocked, "blocked noids", t)
+	assertEqualUint64(15, impact.Estimate(), "estimated blocked noids", t)
+
+	impact = m.BlocklistImpact(16)
+	if impact.Exhaustive {
+		t.Errorf("Expected a sample when checks are limited")
+	}
+	assertEqualUint64(16, impact.Checked, "sampled noids", t)
+	if impact.Estimate() == 0 || impact.Estimate() > 64 {
+		t.Errorf("Expected a reasonable estimate, got %d", impact.Estimate())
+	}
+
+	big, _ := NewMinter("reeeeeeeeeeee")
+	big.Block("a")
+	impact = big.BlocklistImpact(10000)
+	assertEqualUint64(10000, impact.Checked, "sampled noids in a big template", t)
+}
+
+func TestBlocklistIsPersisted(t *testing.T) {
+	m, _ := NewMinter("reeee")
+	m.Block("fu")
+	m.BlockPattern("(?i)^x")
+
+	var buf bytes.Buffer
+	if err := m.WriteJSON(&buf); err != nil {
+		t.Fatalf("Unable to write minter: %s", err)
+	}
+	m2, err := NewMinterFromJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unable to read minter: %s", err)
+	}
+
+	for i := 0; i < 5000; i++ {
+		assertEqualS(m.Mint(), m2.Mint(), "minting after a round trip", t)
+	}
+}
@@ -0,0 +1,269 @@
+package noid
+
+// This file handles the algorithms for computing a noid's check digit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheckDigit is an algorithm for computing the check character appended to a
+// noid whose template ends in "k"
+type CheckDigit interface {
+	// Name identifies the algorithm in persisted minter state
+	Name() string
+
+	// Compute returns the check character for the given string
+	Compute(s string) rune
+}
+
+// CheckCharacters may be implemented by a CheckDigit to list every character
+// Compute can return, which lets Template.Regexp match check digits more
+// tightly than "any character"
+type CheckCharacters interface {
+	Characters() string
+}
+
+// CheckedCharacters may be implemented by a CheckDigit which skips some
+// characters, to list the ones Compute does look at.  Templates using it may
+// only use mask characters whose alphabets are entirely made of these, since
+// a typo in any other character would go unnoticed.
+type CheckedCharacters interface {
+	Checked() string
+}
+
+type legacyCheckDigit struct{}
+type ncda struct{}
+type iso7064Mod112 struct{}
+type damm struct{}
+type urnNBN struct{}
+
+var (
+	// LegacyCheckDigit is the algorithm this library has always used: similar
+	// to NCDA, but over the 32 extended digits, and using byte positions.  It's
+	// the default so existing minters keep minting the same noids.
+	LegacyCheckDigit CheckDigit = legacyCheckDigit{}
+
+	// NCDA is the NOID Check Digit Algorithm from the NOID spec, which other
+	// NOID tools use.  Characters which aren't betanumeric count as zero, so
+	// "a", "u", and "y" from extended digits aren't protected.
+	NCDA CheckDigit = ncda{}
+
+	// ISO7064Mod112 is ISO 7064 MOD 11-2, as used by ORCID and ISNI.  It only
+	// looks at decimal digits, skipping anything else, so it can only be used
+	// with masks of digits.  Its check character is a digit or "X".
+	ISO7064Mod112 CheckDigit = iso7064Mod112{}
+
+	// Damm is the Damm algorithm.  Like ISO7064Mod112, it skips anything
+	// which isn't a decimal digit, so it can only be used with masks of
+	// digits.  Its check character is always a digit.
+	Damm CheckDigit = damm{}
+
+	// URNNBN is the check digit the German National Library requires on
+	// URN:NBNs.  It has to cover the whole URN, so it only makes sense for
+	// templates whose prefix is the full namespace, e.g.,
+	// "urn:nbn:de:gbv:089-sddddddk", or through a URNNBN profile.
+	URNNBN CheckDigit = urnNBN{}
+)
+
+const decimalDigits = "0123456789"
+
+var checkDigits = map[string]CheckDigit{}
+
+func init() {
+	for _, cd := range []CheckDigit{LegacyCheckDigit, NCDA, ISO7064Mod112, Damm, URNNBN} {
+		checkDigits[cd.Name()] = cd
+	}
+}
+
+// CheckDigitByName returns the check digit algorithm with the given name:
+// "legacy", "ncda", "iso7064-mod11-2", "damm", or "urn-nbn"
+func CheckDigitByName(name string) (CheckDigit, error) {
+	cd, ok := checkDigits[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown check digit algorithm %#v", name)
+	}
+	return cd, nil
+}
+
+// Returns an error if the template's check digit algorithm would skip any
+// character its mask can mint
+func (t *Template) validateCheckDigit() error {
+	cd, ok := t.checkDigit().(CheckedCharacters)
+	if !ok {
+		return nil
+	}
+
+	checked := cd.Checked()
+	for _, char := range t.Mask {
+		for _, r := range Alphabet(char) {
+			if !strings.ContainsRune(checked, r) {
+				return fmt.Errorf("The %s check digit only covers %#v, but mask character %#v can mint %#v", t.checkDigit().Name(), checked, string(char), string(r))
+			}
+		}
+	}
+	return nil
+}
+
+// Returns true if the two templates mint the same noids in the same form.
+// Template strings alone don't say which check digit algorithm or grouping
+// is used, so those are compared too.
+func (t *Template) sameAs(other *Template) bool {
+	if t.String() != other.String() || t.GroupSize != other.GroupSize {
+		return false
+	}
+	if t.GroupSize > 0 && (t.groupSeparator() != other.groupSeparator() || t.GroupFromRight != other.GroupFromRight) {
+		return false
+	}
+
+	a, b := t.checkDigit(), other.checkDigit()
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Name() == b.Name()
+}
+
+func (legacyCheckDigit) Name() string {
+	return "legacy"
+}
+
+func (legacyCheckDigit) Compute(s string) rune {
+	return computeCheckDigit(s)
+}
+
+func (legacyCheckDigit) Characters() string {
+	return ExtendedDigits
+}
+
+func computeCheckDigit(s string) rune {
+	tally := 0
+	runes := []rune(ExtendedDigits)
+	for index, ch := range s {
+		idx := strings.IndexRune(ExtendedDigits, ch)
+		if idx == -1 {
+			idx = 0
+		}
+		tally += idx * (1 + index)
+	}
+	return runes[tally%len(ExtendedDigits)]
+}
+
+func (ncda) Name() string {
+	return "ncda"
+}
+
+func (ncda) Characters() string {
+	return betanumerics
+}
+
+// Each character's betanumeric ordinal is multiplied by its (1-based)
+// position, and the sum modulo 29 picks the check character
+func (ncda) Compute(s string) rune {
+	tally := 0
+	for pos, ch := range []rune(s) {
+		if idx := strings.IndexRune(betanumerics, ch); idx != -1 {
+			tally += idx * (pos + 1)
+		}
+	}
+	return rune(betanumerics[tally%len(betanumerics)])
+}
+
+func (iso7064Mod112) Name() string {
+	return "iso7064-mod11-2"
+}
+
+func (iso7064Mod112) Characters() string {
+	return "0123456789X"
+}
+
+func (iso7064Mod112) Checked() string {
+	return decimalDigits
+}
+
+func (iso7064Mod112) Compute(s string) rune {
+	total := 0
+	for _, ch := range s {
+		if ch >= '0' && ch <= '9' {
+			total = (total + int(ch-'0')) * 2 % 11
+		}
+	}
+
+	result := (12 - total%11) % 11
+	if result == 10 {
+		return 'X'
+	}
+	return rune('0' + result)
+}
+
+func (damm) Name() string {
+	return "damm"
+}
+
+var dammTable = [10][10]byte{
+	{0, 3, 1, 7, 5, 9, 8, 6, 4, 2},
+	{7, 0, 9, 2, 1, 5, 4, 8, 6, 3},
+	{4, 2, 0, 6, 8, 7, 1, 3, 5, 9},
+	{1, 7, 5, 0, 9, 8, 3, 4, 2, 6},
+	{6, 1, 2, 3, 0, 4, 5, 9, 7, 8},
+	{3, 6, 7, 4, 2, 0, 9, 5, 8, 1},
+	{5, 8, 6, 9, 7, 2, 0, 1, 3, 4},
+	{8, 9, 4, 5, 3, 6, 2, 0, 1, 7},
+	{9, 4, 3, 8, 6, 1, 7, 2, 0, 5},
+	{2, 5, 8, 1, 4, 3, 6, 7, 9, 0},
+}
+
+func (damm) Characters() string {
+	return decimalDigits
+}
+
+func (damm) Checked() string {
+	return decimalDigits
+}
+
+func (damm) Compute(s string) rune {
+	var interim byte
+	for _, ch := range s {
+		if ch >= '0' && ch <= '9' {
+			interim = dammTable[interim][ch-'0']
+		}
+	}
+	return rune('0' + interim)
+}
+
+func (urnNBN) Name() string {
+	return "urn-nbn"
+}
+
+func (urnNBN) Characters() string {
+	return decimalDigits
+}
+
+// Maps each character URN:NBNs may use to the digits which stand in for it
+var urnNBNDigits = map[rune]string{
+	'0': "1", '1': "2", '2': "3", '3': "4", '4': "5", '5': "6", '6': "7", '7': "8", '8': "9", '9': "41",
+	'a': "18", 'b': "14", 'c': "19", 'd': "15", 'e': "16", 'f': "21", 'g': "22", 'h': "23", 'i': "24",
+	'j': "25", 'k': "42", 'l': "26", 'm': "27", 'n': "13", 'o': "28", 'p': "29", 'q': "31", 'r': "12",
+	's': "32", 't': "33", 'u': "11", 'v': "34", 'w': "35", 'x': "36", 'y': "37", 'z': "38",
+	'-': "39", ':': "17", '_': "43", '/': "45", '.': "47", '+': "49",
+}
+
+// URNs are case-insensitive, so the string is lowercased and converted to
+// digits.  Each digit is multiplied by its (1-based) position, the sum is
+// divided by the last digit, and the quotient's last digit is the check
+// digit.  No character converts to digits ending in zero, so the division is
+// always safe.
+func (urnNBN) Compute(s string) rune {
+	var digits []byte
+	for _, ch := range strings.ToLower(s) {
+		digits = append(digits, urnNBNDigits[ch]...)
+	}
+	if len(digits) == 0 {
+		return '0'
+	}
+
+	sum := 0
+	for i, d := range digits {
+		sum += int(d-'0') * (i + 1)
+	}
+	return rune('0' + sum/int(digits[len(digits)-1]-'0')%10)
+}
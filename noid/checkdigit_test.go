@@ -0,0 +1,111 @@
+package noid
+
+import (
+	"bytes"
+	"testing"
+)
+
+func assertCheckDigit(cd CheckDigit, s string, expected rune, t *testing.T) {
+	if actual := cd.Compute(s); actual != expected {
+		t.Errorf("Expected %s check digit for %#v to be %q, got %q", cd.Name(), s, expected, actual)
+	}
+}
+
+func TestNCDA(t *testing.T) {
+	// The worked example from the NOID spec
+	assertCheckDigit(NCDA, "13030/xf93gt2", 'q', t)
+	assertCheckDigit(NCDA, "", '0', t)
+}
+
+func TestISO7064Mod112(t *testing.T) {
+	// Known-good ORCID iDs
+	assertCheckDigit(ISO7064Mod112, "0000-0002-1825-009", '7', t)
+	assertCheckDigit(ISO7064Mod112, "0000-0002-1694-233", 'X', t)
+}
+
+func TestDamm(t *testing.T) {
+	assertCheckDigit(Damm, "572", '4', t)
+	assertCheckDigit(Damm, "x.5-7:2", '4', t)
+}
+
+func TestDigitOnlyCheckDigitsNeedDigitMasks(t *testing.T) {
+	for _, cd := range []CheckDigit{ISO7064Mod112, Damm} {
+		template, _ := NewTemplate("x.reeek")
+		template.CheckDigit = cd
+		if _, err := NewTemplateMinter(template, 0); err == nil {
+			t.Errorf("Expected %s to be rejected for a mask with letters", cd.Name())
+		}
+
+		template, _ = NewTemplate("x.rdddk")
+		template.CheckDigit = cd
+		if _, err := NewTemplateMinter(template, 0); err != nil {
+			t.Errorf("Unable to use %s with a mask of digits: %s", cd.Name(), err)
+		}
+	}
+
+	// Without a check digit, any mask is fine
+	template, _ := NewTemplate("x.reee")
+	template.CheckDigit = Damm
+	if _, err := NewTemplateMinter(template, 0); err != nil {
+		t.Errorf("Unable to set an algorithm on a template with no check digit: %s", err)
+	}
+}
+
+func TestLegacyIsTheDefault(t *testing.T) {
+	template, _ := NewTemplate("foo.seedeek")
+	minter, _ := NewTemplateMinter(template, 0)
+	assertEqualS("foo.00000f", minter.Mint(), "default check digit", t)
+
+	template.CheckDigit = LegacyCheckDigit
+	minter, _ = NewTemplateMinter(template, 0)
+	assertEqualS("foo.00000f", minter.Mint(), "explicit legacy check digit", t)
+}
+
+func TestARKsWithNCDA(t *testing.T) {
+	template, _ := NewTemplate("ark:/13030/xf9sdeedk")
+	template.CheckDigit = NCDA
+	if err := template.Validate("ark:/13030/xf93gt2q"); err != nil {
+		t.Errorf("Expected the NOID spec's example to be valid: %s", err)
+	}
+	if err := template.Validate("ark:/13030/xf93gt2r"); err == nil {
+		t.Errorf("Expected a bad NCDA check digit to be invalid")
+	}
+}
+
+func TestSelectedCheckDigitIsUsedAndPersisted(t *testing.T) {
+	var buf bytes.Buffer
+
+	for _, name := range []string{"ncda", "iso7064-mod11-2", "damm"} {
+		cd, err := CheckDigitByName(name)
+		if err != nil {
+			t.Fatalf("Unable to find %#v: %s", name, err)
+		}
+
+		template, _ := NewTemplate("12.sddddk")
+		template.CheckDigit = cd
+		minter, _ := NewTemplateMinter(template, 0)
+		for i := 0; i < 50; i++ {
+			minter.Mint()
+		}
+		id := minter.Mint()
+		last := []rune(id)[len(id)-1]
+		assertCheckDigit(cd, id[:len(id)-1], last, t)
+
+		buf.Reset()
+		minter.WriteJSON(&buf)
+		minter, err = NewMinterFromJSON(&buf)
+		if err != nil {
+			t.Fatalf("Unable to read minter: %s", err)
+		}
+		if minter.template.CheckDigit != cd {
+			t.Errorf("Expected %s check digit to survive serialization", name)
+		}
+		if err = minter.template.Validate(id); err != nil {
+			t.Errorf("Expected %#v to validate after a round trip: %s", id, err)
+		}
+	}
+
+	if _, err := CheckDigitByName("luhn"); err == nil {
+		t.Errorf("Expected an unknown check digit name to be an error")
+	}
+}
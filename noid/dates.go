@@ -0,0 +1,247 @@
+package noid
+
+// This file handles templates whose prefix holds the date, e.g.,
+// "{YYYY}.reeed", where each date "bucket" gets its own sequence
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Describes a date placeholder: how it's written in a template, the pattern
+// its values match, and how to fill it in
+type datePlaceholder struct {
+	token   string
+	pattern string
+	format  func(time.Time) string
+}
+
+var datePlaceholders = []datePlaceholder{
+	{"{YYYY}", "[0-9]{4}", func(t time.Time) string { return fmt.Sprintf("%04d", t.Year()) }},
+	{"{MM}", "(?:0[1-9]|1[0-2])", func(t time.Time) string { return fmt.Sprintf("%02d", int(t.Month())) }},
+	{"{DD}", "(?:0[1-9]|[12][0-9]|3[01])", func(t time.Time) string { return fmt.Sprintf("%02d", t.Day()) }},
+}
+
+// BucketState is the sequence and exhaustion of one date bucket, and the
+// sequence values minting skipped in it because they were blocked
+type BucketState struct {
+	Sequence  uint64
+	Exhausted bool            `json:",omitempty"`
+	Skipped   []SequenceRange `json:",omitempty"`
+}
+
+// Returns true if the template's prefix has date placeholders
+func (t *Template) dated() bool {
+	return strings.Contains(t.Prefix, "{")
+}
+
+// Splits a prefix into literal text and placeholders, returning an error for
+// anything in braces which isn't a known placeholder
+func splitDatePrefix(prefix string) ([]string, error) {
+	var parts []string
+	for prefix != "" {
+		start := strings.IndexAny(prefix, "{}")
+		if start == -1 {
+			return append(parts, prefix), nil
+		}
+		if start > 0 {
+			parts = append(parts, prefix[:start])
+			prefix = prefix[start:]
+		}
+
+		end := strings.Index(prefix, "}")
+		if prefix[0] == '}' || end == -1 {
+			return nil, fmt.Errorf("Unbalanced braces in prefix %#v", prefix)
+		}
+		if lookupDatePlaceholder(prefix[:end+1]) == nil {
+			return nil, fmt.Errorf("Unknown date placeholder %#v: expected {YYYY}, {MM}, or {DD}", prefix[:end+1])
+		}
+		parts = append(parts, prefix[:end+1])
+		prefix = prefix[end+1:]
+	}
+
+	return parts, nil
+}
+
+func lookupDatePlaceholder(token string) *datePlaceholder {
+	for i := range datePlaceholders {
+		if datePlaceholders[i].token == token {
+			return &datePlaceholders[i]
+		}
+	}
+	return nil
+}
+
+// Returns a regular expression for the template's prefix, with placeholders
+// replaced by the patterns their values match
+func (t *Template) prefixPattern() string {
+	parts, _ := splitDatePrefix(t.Prefix)
+	var b strings.Builder
+	for _, part := range parts {
+		if p := lookupDatePlaceholder(part); p != nil {
+			b.WriteString(p.pattern)
+		} else {
+			b.WriteString(regexp.QuoteMeta(part))
+		}
+	}
+	return b.String()
+}
+
+// BucketFor returns the date bucket the given time falls in: the template's
+// prefix with its placeholders filled in, e.g., "2026" for "{YYYY}.reeed".
+// Templates without placeholders have a single bucket, "".
+func (t *Template) BucketFor(now time.Time) string {
+	if !t.dated() {
+		return ""
+	}
+
+	parts, _ := splitDatePrefix(t.Prefix)
+	var b strings.Builder
+	for _, part := range parts {
+		if p := lookupDatePlaceholder(part); p != nil {
+			b.WriteString(p.format(now))
+		} else {
+			b.WriteString(part)
+		}
+	}
+	return b.String()
+}
+
+// Returns a copy of the template with the bucket in place of the dated prefix
+func (t *Template) forBucket(bucket string) *Template {
+	if !t.dated() {
+		return t
+	}
+
+	c := *t
+	c.Prefix = bucket
+	return &c
+}
+
+// Returns the template for the bucket the noid's prefix names.  Templates
+// without placeholders are returned as-is.
+func (t *Template) resolve(id string) (*Template, error) {
+	if !t.dated() {
+		return t, nil
+	}
+
+	re, err := regexp.Compile("^(" + t.prefixPattern() + ")" + regexp.QuoteMeta(t.Separator))
+	if err != nil {
+		return nil, err
+	}
+	match := re.FindStringSubmatch(id)
+	if match == nil {
+		return nil, fmt.Errorf("%#v doesn't start with a date matching %#v", id, t.Prefix+t.Separator)
+	}
+
+	return t.forBucket(match[1]), nil
+}
+
+// Bucket validates a noid and returns the date bucket it belongs to, e.g.,
+// "2026" for "2026.q67j4" from the template "{YYYY}.reeed"
+func (t *Template) Bucket(id string) (string, error) {
+	r, err := t.resolve(id)
+	if err != nil {
+		return "", err
+	}
+	if err = r.Validate(id); err != nil {
+		return "", err
+	}
+	if !t.dated() {
+		return "", nil
+	}
+	return r.Prefix, nil
+}
+
+// SetClock replaces the function the minter uses to find the current date
+// bucket.  By default, this is time.Now in UTC, so minters in different time
+// zones, or on either side of a daylight saving change, agree on which bucket
+// an instant falls in.  Buckets come from whatever location the clock's times
+// are in, so a custom clock can use local dates if that's really wanted.
+func (m *Minter) SetClock(clock func() time.Time) {
+	m.clock = clock
+}
+
+func (m *Minter) now() time.Time {
+	if m.clock == nil {
+		return time.Now().UTC()
+	}
+	return m.clock()
+}
+
+// Bucket returns the date bucket the minter last minted in, or "" if it hasn't
+// minted anything yet or its template has no date placeholders
+func (m *Minter) Bucket() string {
+	return m.bucket
+}
+
+// Buckets returns the state of every date bucket the minter has minted in
+func (m *Minter) Buckets() map[string]BucketState {
+	buckets := make(map[string]BucketState, len(m.buckets)+1)
+	for b, state := range m.buckets {
+		buckets[b] = state
+	}
+	if m.bucket != "" {
+		buckets[m.bucket] = m.currentBucketState()
+	}
+	return buckets
+}
+
+func (m *Minter) currentBucketState() BucketState {
+	return BucketState{Sequence: m.generator.Sequence(), Exhausted: m.exhausted, Skipped: m.skipped}
+}
+
+// Returns the template noids are currently being minted from, with the date
+// bucket filled in
+func (m *Minter) active() *Template {
+	return m.template.forBucket(m.bucket)
+}
+
+// Switches the minter to the current date bucket if it isn't already there,
+// saving the old bucket's state.  New buckets start at the minter's shard ID.
+// A minter which has never minted keeps its starting sequence for its first
+// bucket.
+func (m *Minter) rollBucket() {
+	if !m.template.dated() {
+		return
+	}
+
+	bucket := m.template.BucketFor(m.now())
+	if bucket == m.bucket {
+		return
+	}
+
+	// The map is copied rather than changed in place, since Peek shares it
+	buckets := make(map[string]BucketState, len(m.buckets)+1)
+	for b, state := range m.buckets {
+		buckets[b] = state
+	}
+	if m.bucket != "" {
+		buckets[m.bucket] = m.currentBucketState()
+	}
+
+	state, ok := buckets[bucket]
+	if !ok {
+		state.Sequence = m.Shard().ID
+	}
+	if ok || m.bucket != "" {
+		m.generator = NewSuffixGenerator(m.template, state.Sequence)
+		m.exhausted = state.Exhausted
+		m.skipped = state.Skipped
+	}
+	delete(buckets, bucket)
+
+	m.buckets = buckets
+	m.bucket = bucket
+}
+
+// Returns the state of the given bucket
+func (m *Minter) bucketState(bucket string) (BucketState, bool) {
+	if bucket == m.bucket {
+		return m.currentBucketState(), true
+	}
+	state, ok := m.buckets[bucket]
+	return state, ok
+}
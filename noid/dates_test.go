@@ -0,0 +1,188 @@
+package noid
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A clock for tests which only moves when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestDatedTemplates(t *testing.T) {
+	for _, str := range []string{"{YYYY}.reeed", "{YYYY}-{MM}/seeek", "acc{YYYY}{MM}{DD}_zdd"} {
+		template, err := NewTemplate(str)
+		if err != nil {
+			t.Errorf("Unable to parse %#v: %s", str, err)
+			continue
+		}
+		assertEqualS(str, template.String(), "dated template string", t)
+	}
+
+	for _, str := range []string{"{YYYY.reeed", "{YY}.reeed", "YYYY}.reeed", "{yyyy}.reeed"} {
+		if _, err := NewTemplate(str); err == nil {
+			t.Errorf("Expected %#v to be an invalid template", str)
+		}
+	}
+
+	template, _ := NewTemplate("acc{YYYY}-{MM}{DD}_zdd")
+	date := time.Date(2026, time.March, 7, 12, 0, 0, 0, time.UTC)
+	assertEqualS("acc2026-0307", template.BucketFor(date), "bucket", t)
+}
+
+func TestDatedMinting(t *testing.T) {
+	clock := &fakeClock{time.Date(2026, time.December, 31, 23, 0, 0, 0, time.UTC)}
+	m, _ := NewMinter("{YYYY}.sdd")
+	m.SetClock(clock.Now)
+
+	assertEqualS("2026.00", m.Mint(), "first noid of 2026", t)
+	assertEqualS("2026.01", m.Mint(), "second noid of 2026", t)
+	assertEqualS("2026", m.Bucket(), "current bucket", t)
+
+	clock.now = clock.now.Add(2 * time.Hour)
+	assertEqualS("2027.00", m.Mint(), "first noid of 2027", t)
+	assertEqualUint64(1, m.Sequence(), "sequence for 2027", t)
+
+	// Going back picks up where the old bucket left off
+	clock.now = clock.now.Add(-2 * time.Hour)
+	assertEqualS("2026.02", m.Mint(), "third noid of 2026", t)
+
+	buckets := m.Buckets()
+	assertEqualUint64(3, buckets["2026"].Sequence, "2026 sequence", t)
+	assertEqualUint64(1, buckets["2027"].Sequence, "2027 sequence", t)
+
+	for _, tc := range []struct {
+		id     string
+		minted bool
+	}{{"2026.01", true}, {"2026.03", false}, {"2027.00", true}, {"2027.01", false}, {"2025.00", false}} {
+		minted, err := m.Minted(tc.id)
+		if err != nil {
+			t.Errorf("Unexpected error for %#v: %s", tc.id, err)
+		}
+		if minted != tc.minted {
+			t.Errorf("Expected minted for %#v to be %v", tc.id, tc.minted)
+		}
+	}
+}
+
+func TestDatedExhaustionIsPerBucket(t *testing.T) {
+	clock := &fakeClock{time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)}
+	m, _ := NewMinter("{YYYY}{MM}-sd")
+	m.SetClock(clock.Now)
+
+	for i := 0; i < 8; i++ {
+		m.Mint()
+	}
+	if !m.Exhausted() {
+		t.Errorf("Expected January to be exhausted")
+	}
+	assertEqualS("", m.Mint(), "exhausted bucket", t)
+
+	clock.now = clock.now.AddDate(0, 0, 1)
+	if m.Exhausted() {
+		t.Errorf("Expected February not to be exhausted")
+	}
+	assertEqualS("202602-0", m.Mint(), "first noid in February", t)
+}
+
+func TestDecodingDatedNoids(t *testing.T) {
+	template, _ := NewTemplate("{YYYY}-{MM}.reedk")
+	m, _ := NewTemplateMinter(template, 0)
+	m.SetClock((&fakeClock{time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)}).Now)
+
+	for i := uint64(0); i < 50; i++ {
+		id := m.Mint()
+		if !strings.HasPrefix(id, "2025-06.") {
+			t.Fatalf("Expected %#v to be in the June 2025 bucket", id)
+		}
+
+		bucket, err := template.Bucket(id)
+		if err != nil {
+			t.Fatalf("Unable to get bucket for %#v: %s", id, err)
+		}
+		assertEqualS("2025-06", bucket, "bucket of "+id, t)
+
+		seq, _ := template.Decode(id)
+		assertEqualUint64(i, seq, "decoded "+id, t)
+	}
+
+	for _, id := range []string{"2025-13.000k", "25-06.000k", "{YYYY}-{MM}.000k"} {
+		if err := template.Validate(id); err == nil {
+			t.Errorf("Expected %#v to be invalid", id)
+		}
+	}
+	if !template.Regexp().MatchString(m.Mint()) {
+		t.Errorf("Expected the pattern to match dated noids")
+	}
+}
+
+func TestDatedStateIsPersisted(t *testing.T) {
+	clock := &fakeClock{time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC)}
+	m, _ := NewMinter("{YYYY}.reee")
+	m.SetClock(clock.Now)
+	m.Mint()
+	clock.now = clock.now.AddDate(1, 0, 0)
+	m.Mint()
+	m.Mint()
+
+	var buf bytes.Buffer
+	if err := m.WriteJSON(&buf); err != nil {
+		t.Fatalf("Unable to write minter: %s", err)
+	}
+	m2, err := NewMinterFromJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unable to read minter: %s", err)
+	}
+	m2.SetClock(clock.Now)
+
+	assertEqualS(m.Mint(), m2.Mint(), "minting in the current bucket", t)
+	clock.now = clock.now.AddDate(-1, 0, 0)
+	assertEqualS(m.Mint(), m2.Mint(), "minting in an old bucket", t)
+}
+
+func TestRecoveringDatedNoids(t *testing.T) {
+	input := "2025.03\n2026.01\n2025.05\n"
+	m, bad, err := Recover("{YYYY}.sdd", strings.NewReader(input))
+	if err != nil || len(bad) != 0 {
+		t.Fatalf("Unable to recover: %v %v", err, bad)
+	}
+
+	clock := &fakeClock{time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)}
+	m.SetClock(clock.Now)
+	assertEqualS("2025.06", m.Mint(), "recovered 2025 bucket", t)
+	clock.now = clock.now.AddDate(1, 0, 0)
+	assertEqualS("2026.02", m.Mint(), "recovered 2026 bucket", t)
+	clock.now = clock.now.AddDate(1, 0, 0)
+	assertEqualS("2027.00", m.Mint(), "new bucket", t)
+}
+
+func TestDatedTemplatesCantHold(t *testing.T) {
+	m, _ := NewMinter("{YYYY}.sdd")
+	if err := m.Hold("2026.01"); err == nil {
+		t.Errorf("Expected an error holding a dated noid")
+	}
+	if err := m.HoldRange(1, 2); err == nil {
+		t.Errorf("Expected an error holding a range for a dated template")
+	}
+
+	var sm SerializeableMinter
+	json.Unmarshal([]byte(`{"Template":"{YYYY}.sdd","Holds":[{"Start":1,"End":1}]}`), &sm)
+	if _, err := sm.minter(); err == nil {
+		t.Errorf("Expected an error restoring holds for a dated template")
+	}
+}
+
+func TestDefaultClockIsUTC(t *testing.T) {
+	m, _ := NewMinter("{YYYY}.sdd")
+	if loc := m.now().Location(); loc != time.UTC {
+		t.Errorf("Expected the default clock to be in UTC, got %s", loc)
+	}
+}
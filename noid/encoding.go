@@ -4,21 +4,519 @@ package noid
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 )
 
 // Instead of making a minter expose everything and serializing tons of
-// internals, we really only care about the sequence and template data
+// internals, we really only care about the sequence and template data, plus
+// holds, the blocklist and what it made minting skip, date buckets, the
+// shard, leases, the templates the minter has rolled over from and will roll
+// over to, whatever has been bound to minted noids, and their lifecycle
+// statuses
 type SerializeableMinter struct {
-	Template string
-	Sequence uint64
+	Template    string
+	Alphabets   map[string]string `json:",omitempty"`
+	CheckDigit  string            `json:",omitempty"`
+	Grouping    *Grouping         `json:",omitempty"`
+	Separator   *string           `json:",omitempty"`
+	Sequence    uint64
+	Bucket      string                 `json:",omitempty"`
+	Buckets     map[string]BucketState `json:",omitempty"`
+	Exhausted   bool                   `json:",omitempty"`
+	Holds       []SequenceRange        `json:",omitempty"`
+	Skipped     []SequenceRange        `json:",omitempty"`
+	Blocklist   *Blocklist             `json:",omitempty"`
+	Shard       *Shard                 `json:",omitempty"`
+	Lease       *Lease                 `json:",omitempty"`
+	Leases      []Lease                `json:",omitempty"`
+	Returned    []SequenceRange        `json:",omitempty"`
+	Generations []StoredGeneration     `json:",omitempty"`
+	Rollover    []StoredTemplate       `json:",omitempty"`
+	Bindings    map[string]Bindings    `json:",omitempty"`
+	Statuses    map[string]*Lifecycle  `json:",omitempty"`
+}
+
+// Grouping holds a template's display grouping options in serialized minters
+type Grouping struct {
+	Size      int
+	Separator string `json:",omitempty"`
+	FromRight bool   `json:",omitempty"`
+}
+
+// StoredTemplate holds a template, and the settings which aren't part of its
+// string, in serialized minters.  Separator is only set for templates whose
+// separator was set with SetSeparator; it's a pointer since "" (no
+// separator) is a valid choice.
+type StoredTemplate struct {
+	Template   string
+	CheckDigit string    `json:",omitempty"`
+	Grouping   *Grouping `json:",omitempty"`
+	Separator  *string   `json:",omitempty"`
+}
+
+// StoredGeneration holds a template a minter has rolled over from, along with
+// the sequence values in it which were held, handed back from leases, or
+// skipped for being blocked, in serialized minters
+type StoredGeneration struct {
+	StoredTemplate
+	Holds    []SequenceRange `json:",omitempty"`
+	Returned []SequenceRange `json:",omitempty"`
+	Skipped  []SequenceRange `json:",omitempty"`
+}
+
+func storedTemplate(t *Template) StoredTemplate {
+	st := StoredTemplate{Template: t.String()}
+	if t.CheckDigit != nil {
+		st.CheckDigit = t.CheckDigit.Name()
+	}
+	if t.GroupSize > 0 {
+		st.Grouping = &Grouping{t.GroupSize, t.GroupSeparator, t.GroupFromRight}
+	}
+	if t.customSeparator() {
+		sep := t.Separator
+		st.Separator = &sep
+	}
+	return st
+}
+
+func storedTemplates(templates []*Template) []StoredTemplate {
+	var stored []StoredTemplate
+	for _, t := range templates {
+		stored = append(stored, storedTemplate(t))
+	}
+	return stored
+}
+
+// Returns an error if any of the ranges is backward or past max
+func validateRanges(max uint64, lists ...[]SequenceRange) error {
+	for _, list := range lists {
+		for _, r := range list {
+			if err := r.validate(max); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Minter) storedGenerations() []StoredGeneration {
+	var stored []StoredGeneration
+	for _, g := range m.generations {
+		stored = append(stored, StoredGeneration{storedTemplate(g.template), g.holds, g.returned, g.skipped})
+	}
+	return stored
+}
+
+func (st StoredTemplate) template() (*Template, error) {
+	t, err := NewTemplate(st.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	if st.CheckDigit != "" {
+		t.CheckDigit, err = CheckDigitByName(st.CheckDigit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if st.Grouping != nil {
+		t.GroupSize = st.Grouping.Size
+		t.GroupSeparator = st.Grouping.Separator
+		t.GroupFromRight = st.Grouping.FromRight
+	}
+
+	if st.Separator != nil {
+		if err = t.SetSeparator(*st.Separator); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// MarshalText returns the template string, followed by "?" and URL-encoded
+// settings the template string can't hold, if there are any, e.g.,
+// "x.reedeek?check-digit=ncda&group=3", or "foo+reedeek?separator=%2B" for a
+// separator set with SetSeparator.  Custom alphabets the mask uses are
+// included, as "alphabet=C:CHARS" sorted by mask character, so unmarshaling
+// can make sure the template means the same thing wherever it's read.
+func (t Template) MarshalText() ([]byte, error) {
+	st := storedTemplate(&t)
+	v := url.Values{}
+	if st.CheckDigit != "" {
+		v.Set("check-digit", st.CheckDigit)
+	}
+	if g := st.Grouping; g != nil {
+		v.Set("group", strconv.Itoa(g.Size))
+		if g.Separator != "" {
+			v.Set("group-separator", g.Separator)
+		}
+		if g.FromRight {
+			v.Set("group-from", "right")
+		}
+	}
+	if st.Separator != nil {
+		v.Set("separator", *st.Separator)
+	}
+	alphabets := t.customAlphabets()
+	var chars []string
+	for char := range alphabets {
+		chars = append(chars, char)
+	}
+	for _, char := range sortedStrings(chars) {
+		v.Add("alphabet", char+":"+alphabets[char])
+	}
+
+	if len(v) == 0 {
+		return []byte(st.Template), nil
+	}
+	return []byte(st.Template + "?" + v.Encode()), nil
+}
+
+// UnmarshalText parses what MarshalText returns.  Plain template strings work
+// too.  Text can come from anywhere, so the alphabets it carries are never
+// registered: their mask characters must already be registered, with the
+// same alphabets, or an error is returned.  Call RegisterAlphabet first to
+// accept a template's custom alphabets.
+func (t *Template) UnmarshalText(text []byte) error {
+	s := string(text)
+	st := StoredTemplate{Template: s}
+	var alphabets []string
+
+	// Template prefixes can hold a "?", so what follows the last one is only
+	// treated as settings if it parses as such
+	if i := strings.LastIndex(s, "?"); i != -1 {
+		if v, err := url.ParseQuery(s[i+1:]); err == nil && templateSettings(v) {
+			st.Template = s[:i]
+			st.CheckDigit = v.Get("check-digit")
+			if size := v.Get("group"); size != "" {
+				st.Grouping = &Grouping{Separator: v.Get("group-separator"), FromRight: v.Get("group-from") == "right"}
+				if st.Grouping.Size, err = strconv.Atoi(size); err != nil {
+					return fmt.Errorf("Invalid group size %#v", size)
+				}
+				if from := v.Get("group-from"); from != "" && from != "left" && from != "right" {
+					return fmt.Errorf(`Invalid group side %#v: expected "left" or "right"`, from)
+				}
+			}
+			if seps, ok := v["separator"]; ok {
+				st.Separator = &seps[0]
+			}
+			alphabets = v["alphabet"]
+		}
+	}
+
+	for _, a := range alphabets {
+		runes := []rune(a)
+		if len(runes) < 3 || runes[1] != ':' {
+			return fmt.Errorf(`Invalid alphabet %#v: expected "C:CHARS"`, a)
+		}
+		if err := checkAlphabet(runes[0], string(runes[2:])); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := st.template()
+	if err != nil {
+		return err
+	}
+	m, err := NewTemplateMinter(parsed, 0)
+	if err != nil {
+		return err
+	}
+
+	*t = *m.template
+	return nil
+}
+
+// Returns true if every key is one of the settings MarshalText writes
+func templateSettings(v url.Values) bool {
+	if len(v) == 0 {
+		return false
+	}
+	for key := range v {
+		switch key {
+		case "check-digit", "group", "group-separator", "group-from", "separator", "alphabet":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the custom alphabets used by any of the minter's templates
+func (m *Minter) customAlphabets() map[string]string {
+	var alphabets map[string]string
+	for _, t := range append(m.Generations(), m.rollover...) {
+		for char, alphabet := range t.customAlphabets() {
+			if alphabets == nil {
+				alphabets = make(map[string]string)
+			}
+			alphabets[char] = alphabet
+		}
+	}
+	return alphabets
+}
+
+func (m *Minter) serializeable() SerializeableMinter {
+	st := storedTemplate(m.template)
+	sm := SerializeableMinter{
+		Template:    st.Template,
+		Alphabets:   m.customAlphabets(),
+		CheckDigit:  st.CheckDigit,
+		Grouping:    st.Grouping,
+		Separator:   st.Separator,
+		Sequence:    m.Sequence(),
+		Bucket:      m.bucket,
+		Buckets:     m.buckets,
+		Exhausted:   m.exhausted,
+		Holds:       m.Holds(),
+		Skipped:     m.skipped,
+		Lease:       m.Lease(),
+		Leases:      m.leases,
+		Returned:    m.returned,
+		Generations: m.storedGenerations(),
+		Rollover:    storedTemplates(m.rollover),
+		Bindings:    m.bindings,
+		Statuses:    m.statuses,
+	}
+	if m.shard.Count > 1 {
+		shard := m.shard
+		sm.Shard = &shard
+	}
+	if !m.blocklist.empty() {
+		sm.Blocklist = m.blocklist.clone()
+	}
+
+	return sm
+}
+
+// Builds a minter from the serialized data, verifying the template and
+// sequence are still valid.  As with Template.UnmarshalText, the custom
+// alphabets the data carries must already be registered; decoding never
+// registers them.
+func (sm SerializeableMinter) minter() (*Minter, error) {
+	for char, alphabet := range sm.Alphabets {
+		runes := []rune(char)
+		if len(runes) != 1 {
+			return nil, fmt.Errorf("Invalid mask character %#v", char)
+		}
+		if err := checkAlphabet(runes[0], alphabet); err != nil {
+			return nil, err
+		}
+	}
+
+	t, err := StoredTemplate{sm.Template, sm.CheckDigit, sm.Grouping, sm.Separator}.template()
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := NewTemplateMinter(t, sm.Sequence)
+	if err != nil {
+		return nil, err
+	}
+
+	m.exhausted = sm.Exhausted
+
+	if sm.Shard != nil {
+		if err = sm.Shard.validate(m.generator.maxSequence); err != nil {
+			return nil, err
+		}
+		m.shard = *sm.Shard
+	}
+
+	if (sm.Bucket != "" || len(sm.Buckets) > 0) && !t.dated() {
+		return nil, fmt.Errorf("Template %#v has no date placeholders, but the minter has date buckets", sm.Template)
+	}
+	for bucket, state := range sm.Buckets {
+		if state.Sequence > m.generator.maxSequence {
+			return nil, fmt.Errorf("Sequence for bucket %#v is too high", bucket)
+		}
+		if err = validateRanges(m.generator.maxSequence, state.Skipped); err != nil {
+			return nil, fmt.Errorf("Bucket %#v: %s", bucket, err)
+		}
+	}
+	m.bucket = sm.Bucket
+	if len(sm.Buckets) > 0 {
+		m.buckets = sm.Buckets
+	}
+	for _, r := range sm.Holds {
+		if err = m.validateHoldRange(r); err != nil {
+			return nil, err
+		}
+	}
+	m.holds = m.holds.add(sm.Holds...)
+	if err = validateRanges(m.generator.maxSequence, sm.Skipped); err != nil {
+		return nil, err
+	}
+	m.skipped = m.skipped.add(sm.Skipped...)
+
+	if err = sm.restoreRollover(m); err != nil {
+		return nil, err
+	}
+
+	if err = sm.validateLeases(m); err != nil {
+		return nil, err
+	}
+
+	// A stored blocklist was already accepted, so it's restored as-is even if
+	// it now blocks everything; Mint's cap on blocked runs still applies
+	if sm.Blocklist != nil {
+		if err = m.blocklist.addSubstrings(sm.Blocklist.Substrings...); err != nil {
+			return nil, err
+		}
+		if err = m.blocklist.addPatterns(sm.Blocklist.Patterns...); err != nil {
+			return nil, err
+		}
+	}
+
+	for id, b := range sm.Bindings {
+		if err = m.Bind(id, b); err != nil {
+			return nil, err
+		}
+	}
+
+	for id, l := range sm.Statuses {
+		canonical, err := m.Canonical(id)
+		if err != nil {
+			return nil, err
+		}
+		if canonical != id {
+			return nil, fmt.Errorf("Status for %#v isn't stored under its canonical form, %#v", id, canonical)
+		}
+		if l == nil {
+			return nil, fmt.Errorf("Status for %#v is empty", id)
+		}
+	}
+	if len(sm.Statuses) > 0 {
+		m.statuses = sm.Statuses
+	}
+
+	return m, nil
+}
+
+// Puts the minter's old and future templates back, checking the whole chain
+// for collisions again
+func (sm SerializeableMinter) restoreRollover(m *Minter) error {
+	for _, sg := range sm.Generations {
+		t, err := sg.template()
+		if err != nil {
+			return err
+		}
+		gen, err := NewTemplateMinter(t, 0)
+		if err != nil {
+			return err
+		}
+		if t.dated() {
+			return errors.New("Templates with date placeholders can't roll over")
+		}
+		if err = validateRanges(gen.generator.maxSequence, sg.Holds, sg.Returned, sg.Skipped); err != nil {
+			return fmt.Errorf("Generation %#v: %s", sg.Template, err)
+		}
+		var holds, returned, skipped sequenceSet
+		m.generations = append(m.generations, generation{gen.template, holds.add(sg.Holds...), returned.add(sg.Returned...), skipped.add(sg.Skipped...)})
+	}
+
+	var chain []*Template
+	for _, st := range sm.Rollover {
+		t, err := st.template()
+		if err != nil {
+			return err
+		}
+		chain = append(chain, t)
+	}
+	if len(m.generations) > 0 && m.template.dated() {
+		return errors.New("Templates with date placeholders can't roll over")
+	}
+	return m.SetRollover(chain...)
+}
+
+// Makes sure the leases can't overlap each other or anything else the minter
+// might mint, then puts them on the minter
+func (sm SerializeableMinter) validateLeases(m *Minter) error {
+	if sm.Lease == nil && len(sm.Leases) == 0 && len(sm.Returned) == 0 {
+		return nil
+	}
+	if m.template.dated() {
+		return errors.New("Templates with date placeholders can't be leased")
+	}
+
+	if sm.Lease != nil {
+		if len(sm.Leases) > 0 || len(sm.Returned) > 0 {
+			return errors.New("Leased minters can't grant leases of their own")
+		}
+		r := SequenceRange{sm.Lease.Start, sm.Lease.End}
+		if err := r.validate(m.generator.maxSequence); err != nil {
+			return err
+		}
+		if sm.Sequence < r.Start || sm.Sequence > r.End {
+			return errors.New("Sequence is outside the minter's lease")
+		}
+		l := *sm.Lease
+		m.lease = &l
+		return nil
+	}
+
+	// Leases from templates the minter has rolled over from can't overlap
+	// anything it mints now
+	ranges := append([]SequenceRange(nil), sm.Returned...)
+	for _, l := range sm.Leases {
+		if l.Client == "" {
+			return errors.New("Leases must name a client")
+		}
+		if l.Generation < 0 || l.Generation > len(m.generations) {
+			return fmt.Errorf("Lease for %#v is from an unknown generation", l.Client)
+		}
+		if l.Generation == len(m.generations) {
+			ranges = append(ranges, SequenceRange{l.Start, l.End})
+		}
+	}
+	var claimed sequenceSet
+	for _, r := range ranges {
+		if err := r.validate(m.generator.maxSequence); err != nil {
+			return err
+		}
+		for _, c := range claimed {
+			if r.Start <= c.End && r.End >= c.Start {
+				return fmt.Errorf("Sequence values %d-%d are leased or returned more than once", r.Start, r.End)
+			}
+		}
+		if !m.exhausted && r.End >= m.generator.Sequence() {
+			return fmt.Errorf("Sequence values %d-%d are leased or returned, but the minter hasn't reached them", r.Start, r.End)
+		}
+		claimed = claimed.add(r)
+	}
+
+	m.leases = sm.Leases
+	m.returned = m.returned.add(sm.Returned...)
+	return nil
 }
 
 func (m *Minter) WriteJSON(w io.Writer) error {
-	sm := SerializeableMinter{Template: m.Template(), Sequence: m.Sequence()}
 	enc := json.NewEncoder(w)
-	return enc.Encode(sm)
+	return enc.Encode(m.serializeable())
+}
+
+// MarshalJSON returns the same JSON WriteJSON writes, so minters can be
+// embedded in other JSON documents
+func (m *Minter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.serializeable())
+}
+
+// UnmarshalJSON replaces the minter with the one in the given JSON, validating
+// it just as NewMinterFromJSON does.  The minter's clock is kept.
+func (m *Minter) UnmarshalJSON(data []byte) error {
+	var sm SerializeableMinter
+	if err := json.Unmarshal(data, &sm); err != nil {
+		return err
+	}
+	return m.replaceWith(sm)
 }
 
 func NewMinterFromJSON(r io.Reader) (*Minter, error) {
@@ -28,7 +526,7 @@ func NewMinterFromJSON(r io.Reader) (*Minter, error) {
 		return nil, err
 	}
 
-	return NewSequencedMinter(sm.Template, sm.Sequence)
+	return sm.minter()
 }
 
 // Reads the given file and converts its JSON data into a minter
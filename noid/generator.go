@@ -4,6 +4,7 @@ package noid
 
 import (
 	"errors"
+	"fmt"
 	"math"
 )
 
@@ -14,7 +15,11 @@ const ExtendedDigits = "0123456789abcdfghjkmnpqrstuvwxyz"
 // Maximum possible mask within 64 bits (if using "d" for all characters)
 const MaxMaskLength = 21
 
-type SuffixContainer [MaxMaskLength]rune
+// Maximum possible suffix length within 64 bits, if using a registered
+// alphabet of just two characters
+const maxSuffixLength = 64
+
+type SuffixContainer [maxSuffixLength]rune
 
 // TODO: break this down - this type is being used both as a long-lived
 // generator object as well as a temporary, highly mutable data container
@@ -28,21 +33,21 @@ type SuffixContainer [MaxMaskLength]rune
 // - index has no application in a generator and is only used during generation
 // - minLength is set once and referred to during generation, but never altered
 // - suffix is used only during generation
-// - reverseMaskBits is set by the generator, but only needs to be set up just
+// - reverseMask is set by the generator, but only needs to be set up just
 //   prior to generation
 // - totalBits is useful for setting up generation-specific bitswapping on
 //   randomly-ordered noids, but shouldn't be needed during generation
 // - ordering should only be necessary for the generator to set up data the
 //   generation process uses
 type SuffixGenerator struct {
-	sequenceValue   uint64
-	maxSequence     uint64
-	index           int
-	minLength       int
-	suffix          SuffixContainer
-	reverseMaskBits []byte
-	totalBits       byte
-	ordering        Ordering
+	sequenceValue uint64
+	maxSequence   uint64
+	index         int
+	minLength     int
+	suffix        SuffixContainer
+	reverseMask   []*maskCharacter
+	totalBits     byte
+	ordering      Ordering
 }
 
 // Utility for easing the template mask reversal
@@ -57,12 +62,12 @@ func stringReverseRunes(s string) []rune {
 func NewSuffixGenerator(template *Template, sequenceValue uint64) *SuffixGenerator {
 	nsg := &SuffixGenerator{sequenceValue: sequenceValue}
 	nsg.ordering = template.Ordering
-	nsg.minLength = len(template.Mask)
 
 	reverseMask := stringReverseRunes(template.Mask)
-	nsg.reverseMaskBits = make([]byte, nsg.minLength)
+	nsg.minLength = len(reverseMask)
+	nsg.reverseMask = make([]*maskCharacter, nsg.minLength)
 	for i, char := range reverseMask {
-		nsg.reverseMaskBits[i] = bitsForMaskCharacter(char)
+		nsg.reverseMask[i] = lookupMaskCharacter(char)
 	}
 
 	if nsg.ordering == SequentialUnlimited {
@@ -84,29 +89,29 @@ func NewSuffixGenerator(template *Template, sequenceValue uint64) *SuffixGenerat
 func (nsg *SuffixGenerator) computeMaxSequenceValue() {
 	nsg.totalBits = 0
 
-	for _, bits := range nsg.reverseMaskBits {
-		nsg.totalBits += bits
+	for _, mc := range nsg.reverseMask {
+		nsg.totalBits += mc.bits
 	}
 
 	nsg.maxSequence = (1 << nsg.totalBits) - 1
 }
 
-// Shuffles bits and xors stuff to map one sequence to another
+// Returns the value xored with a sequence when randomizing it: 2/3 of the max
+// value gives us a repeating "1010..." bit pattern, which is a decent xor value
+// to start with
+func (nsg *SuffixGenerator) randomXor() uint64 {
+	return nsg.maxSequence * 2 / 3
+}
+
+// Returns the pairs of bits swapped, in order, when randomizing a sequence
 //
 // TODO: Cache bit pairs so we don't recompute this on every single iteration
-func (nsg *SuffixGenerator) randomizeSequence() {
+func (nsg *SuffixGenerator) bitSwapPairs() [][2]byte {
 	var maxBit byte = nsg.totalBits - 1
 	var bitIndex byte
 
-	// 2/3 of the max value gives us a repeating "1010..." bit pattern, which is
-	// a decent xor value to start with
-	xor := nsg.maxSequence * 2 / 3
-
 	// Create a changing seed based on our xor value for bit swapping "randomness"
-	seed := xor
-
-	// Temporary local var to ease code (and possibly avoid indirection)
-	sval := nsg.sequenceValue ^ xor
+	seed := nsg.randomXor()
 
 	// Make sure the lowest bits are distributed a little - we always have at
 	// least three bits, so this will never crash, though it won't necessarily be
@@ -115,18 +120,41 @@ func (nsg *SuffixGenerator) randomizeSequence() {
 	// 3 bits:  0 and 2 			1 and 1
 	// 5 bits:  0 and 3 			1 and 2
 	// 20 bits: 0 and 18			1 and 9
-	sval = bitSwap(sval, 0, maxBit-1)
-	sval = bitSwap(sval, 1, maxBit>>1)
+	pairs := [][2]byte{{0, maxBit - 1}, {1, maxBit >> 1}}
 
 	for bitIndex = 3; bitIndex < maxBit; bitIndex++ {
 		bit2 := seed % uint64(nsg.totalBits)
-		sval = bitSwap(sval, bitIndex, byte(bit2))
+		pairs = append(pairs, [2]byte{bitIndex, byte(bit2)})
 		seed = seed >> 1
 	}
 
+	return pairs
+}
+
+// Shuffles bits and xors stuff to map one sequence to another
+func (nsg *SuffixGenerator) randomizeSequence() {
+	// Temporary local var to ease code (and possibly avoid indirection)
+	sval := nsg.sequenceValue ^ nsg.randomXor()
+
+	for _, pair := range nsg.bitSwapPairs() {
+		sval = bitSwap(sval, pair[0], pair[1])
+	}
+
 	nsg.sequenceValue = sval
 }
 
+// Undoes randomizeSequence: the same swaps in reverse order, then the xor
+func (nsg *SuffixGenerator) unrandomizeSequence() {
+	sval := nsg.sequenceValue
+	pairs := nsg.bitSwapPairs()
+
+	for i := len(pairs) - 1; i >= 0; i-- {
+		sval = bitSwap(sval, pairs[i][0], pairs[i][1])
+	}
+
+	nsg.sequenceValue = sval ^ nsg.randomXor()
+}
+
 func (nsg SuffixGenerator) Sequence() uint64 {
 	return nsg.sequenceValue
 }
@@ -156,28 +184,78 @@ func (nsg *SuffixGenerator) NextSequence() error {
 
 // Based on mask, ordering, and nsg state, prepends the next noid suffix char
 func (nsg *SuffixGenerator) addCharacter() {
-	bits := nsg.reverseMaskBits[0]
-	if len(nsg.reverseMaskBits) > 1 {
-		nsg.reverseMaskBits = nsg.reverseMaskBits[1:]
+	mc := nsg.reverseMask[0]
+	if len(nsg.reverseMask) > 1 {
+		nsg.reverseMask = nsg.reverseMask[1:]
 	}
 
-	val := nsg.sequenceValue & ((1 << bits) - 1)
+	val := nsg.sequenceValue & ((1 << mc.bits) - 1)
 
-	templateChar := rune(ExtendedDigits[val])
-	nsg.suffix[MaxMaskLength-1-nsg.index] = templateChar
-	nsg.sequenceValue >>= bits
+	templateChar := mc.alphabet[val]
+	nsg.suffix[maxSuffixLength-1-nsg.index] = templateChar
+	nsg.sequenceValue >>= mc.bits
 	nsg.index++
 }
 
-func (nsc *SuffixContainer) toString(length int) string {
-	return string(nsc[MaxMaskLength-length : MaxMaskLength])
+// Converts a suffix back into the value its characters represent.  This is
+// the inverse of the character-building half of ToString, so for random
+// templates the value is still the shuffled one.
+func (nsg SuffixGenerator) valueOf(suffix string) (uint64, error) {
+	runes := stringReverseRunes(suffix)
+	if len(runes) < nsg.minLength {
+		return 0, fmt.Errorf("Suffix %#v is too short: need %d characters", suffix, nsg.minLength)
+	}
+	if len(runes) > nsg.minLength {
+		if nsg.ordering != SequentialUnlimited {
+			return 0, fmt.Errorf("Suffix %#v is too long: need %d characters", suffix, nsg.minLength)
+		}
+
+		// Extra characters only show up when there's value left to represent, so
+		// a leading zero means this suffix was never generated
+		if runes[len(runes)-1] == nsg.reverseMask[nsg.minLength-1].alphabet[0] {
+			return 0, fmt.Errorf("Suffix %#v has an extra leading zero", suffix)
+		}
+	}
+
+	var val uint64
+	var shift uint
+	for i, char := range runes {
+		mc := nsg.reverseMask[nsg.minLength-1]
+		if i < nsg.minLength {
+			mc = nsg.reverseMask[i]
+		}
+
+		idx := mc.indexOf(char)
+		if idx == -1 {
+			return 0, fmt.Errorf("Suffix %#v has invalid character %#v", suffix, string(char))
+		}
+
+		if idx != 0 && (shift >= 64 || uint64(idx)>>(64-shift) != 0) {
+			return 0, fmt.Errorf("Suffix %#v is too large", suffix)
+		}
+		val |= uint64(idx) << shift
+		shift += uint(mc.bits)
+	}
+
+	return val, nil
 }
 
-// Returns bit constants for a given mask character
-func bitsForMaskCharacter(char rune) byte {
-	if char == 'd' {
-		return DigitBits
+// Converts a suffix back into the sequence value which generates it
+func (nsg SuffixGenerator) sequenceOf(suffix string) (uint64, error) {
+	val, err := nsg.valueOf(suffix)
+	if err != nil {
+		return 0, err
+	}
+
+	if nsg.ordering == Random {
+		nsg.sequenceValue = val
+		nsg.unrandomizeSequence()
+		val = nsg.sequenceValue
 	}
 
-	return ExtendedDigitBits
+	return val, nil
+}
+
+func (nsc *SuffixContainer) toString(length int) string {
+	return string(nsc[maxSuffixLength-length : maxSuffixLength])
 }
@@ -132,3 +132,21 @@ func TestRandomNoidsDontRepeat(t *testing.T) {
 		err = g.NextSequence()
 	}
 }
+
+func TestSequenceOfUndoesToString(t *testing.T) {
+	for _, str := range []string{"reee", "rdd", "reedeedk", "seedee", "zdd", "redededededededed"} {
+		template, _ := NewTemplate(str)
+		g := NewSuffixGenerator(template, 0)
+
+		for i := 0; i < 5000; i++ {
+			seq, err := g.sequenceOf(g.ToString())
+			if err != nil {
+				t.Fatalf("%s: unable to decode %#v: %s", str, g.ToString(), err)
+			}
+			assertEqualUint64(g.sequenceValue, seq, str+": decoding "+g.ToString(), t)
+			if g.NextSequence() != nil {
+				break
+			}
+		}
+	}
+}
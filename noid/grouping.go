@@ -0,0 +1,89 @@
+package noid
+
+// This file handles splitting long noids into groups for display, e.g.,
+// "ark:/12345/x5gt-7k3m-q"
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// DefaultGroupSeparator is used when a template's GroupSize is set but its
+// GroupSeparator isn't
+const DefaultGroupSeparator = "-"
+
+func (t *Template) groupSeparator() string {
+	if t.GroupSeparator == "" {
+		return DefaultGroupSeparator
+	}
+	return t.GroupSeparator
+}
+
+// Splits s into groups of the template's group size, counting from the left
+// or right as the template specifies
+func (t *Template) group(s string) string {
+	if t.GroupSize <= 0 {
+		return s
+	}
+
+	runes := []rune(s)
+	var groups []string
+
+	// When grouping from the right, the leftover (short) group is on the left
+	first := len(runes) % t.GroupSize
+	if !t.GroupFromRight || first == 0 {
+		first = t.GroupSize
+	}
+	for start, end := 0, first; start < len(runes); start, end = end, end+t.GroupSize {
+		if end > len(runes) {
+			end = len(runes)
+		}
+		groups = append(groups, string(runes[start:end]))
+	}
+
+	return strings.Join(groups, t.groupSeparator())
+}
+
+// Removes group separators from s
+func (t *Template) ungroup(s string) string {
+	if t.GroupSize <= 0 {
+		return s
+	}
+	return strings.Replace(s, t.groupSeparator(), "", -1)
+}
+
+// Makes sure the grouping options can't be confused with the noid itself
+func (t *Template) validateGrouping() error {
+	if t.GroupSize < 0 {
+		return errors.New("Group size cannot be negative")
+	}
+	if t.GroupSize == 0 {
+		return nil
+	}
+
+	for _, r := range t.GroupSeparator {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return errors.New("Group separators cannot contain letters or digits")
+		}
+	}
+	if t.ARK != nil && t.groupSeparator() != "-" {
+		return errors.New(`ARKs can only be grouped with hyphens, since "/" and "." start qualifiers`)
+	}
+
+	return nil
+}
+
+// Returns everything in a minted noid after its prefix (or ARK shoulder),
+// without group separators
+func (t *Template) body(id string) string {
+	if r, err := t.resolve(id); err == nil {
+		t = r
+	}
+	if t.ARK == nil {
+		return t.ungroup(strings.TrimPrefix(id, t.Prefix+t.Separator))
+	}
+
+	rest := t.ungroup(strings.TrimPrefix(id, arkLabel+t.ARK.NAAN+"/"))
+	return strings.TrimPrefix(rest, t.ARK.Shoulder)
+}
@@ -0,0 +1,116 @@
+package noid
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	template := &Template{GroupSize: 4}
+	assertEqualS("abcd-efgh-ij", template.group("abcdefghij"), "grouping from the left", t)
+	assertEqualS("abcd-efgh", template.group("abcdefgh"), "grouping even lengths", t)
+	assertEqualS("abc", template.group("abc"), "grouping short strings", t)
+
+	template.GroupFromRight = true
+	template.GroupSeparator = " "
+	assertEqualS("ab cdef ghij", template.group("abcdefghij"), "grouping from the right", t)
+	assertEqualS("abcd efgh", template.group("abcdefgh"), "grouping even lengths from the right", t)
+
+	template.GroupSize = 0
+	assertEqualS("abcdefghij", template.group("abcdefghij"), "no grouping", t)
+}
+
+func TestGroupedARKs(t *testing.T) {
+	template, _ := NewARKTemplate("12345", "x5", "reeeeeek")
+	template.GroupSize = 4
+	grouped, err := NewTemplateMinter(template, 0)
+	if err != nil {
+		t.Fatalf("Unable to create grouped minter: %s", err)
+	}
+	plain, _ := NewARKMinter("12345", "x5", "reeeeeek")
+
+	for i := 0; i < 50; i++ {
+		id, expected := grouped.Mint(), plain.Mint()
+		name := strings.TrimPrefix(expected, "ark:/12345/")
+		assertEqualS("ark:/12345/"+name[:4]+"-"+name[4:8]+"-"+name[8:], id, "grouped ARK", t)
+
+		if err := grouped.template.Validate(id); err != nil {
+			t.Errorf("Expected %#v to be valid: %s", id, err)
+		}
+		if err := grouped.template.Validate(expected); err != nil {
+			t.Errorf("Expected ungrouped %#v to be valid: %s", expected, err)
+		}
+		seq, err := grouped.template.Decode(id)
+		if err != nil {
+			t.Errorf("Unable to decode %#v: %s", id, err)
+		}
+		assertEqualUint64(uint64(i), seq, "decoded sequence of "+id, t)
+	}
+}
+
+func TestGroupSeparatorsDontAffectCheckDigit(t *testing.T) {
+	template, _ := NewTemplate("foo.seeeeek")
+	template.GroupSize = 2
+	template.GroupSeparator = "_"
+	template.GroupFromRight = true
+	grouped, _ := NewTemplateMinter(template, 0)
+	plain, _ := NewMinter("foo.seeeeek")
+
+	for i := 0; i < 50; i++ {
+		id, expected := grouped.Mint(), plain.Mint()
+		suffix := strings.TrimPrefix(expected, "foo.")
+		assertEqualS("foo."+suffix[:2]+"_"+suffix[2:4]+"_"+suffix[4:], id, "grouped noid", t)
+
+		canonical, err := grouped.template.Canonical(expected)
+		if err != nil {
+			t.Errorf("Unable to canonicalize %#v: %s", expected, err)
+		}
+		assertEqualS(id, canonical, "canonical form of "+expected, t)
+	}
+
+	if err := grouped.template.Validate("foo.12_34_5"); err == nil {
+		t.Errorf("Expected a bad check digit to fail validation")
+	}
+}
+
+func TestInvalidGrouping(t *testing.T) {
+	for _, g := range []struct {
+		template  string
+		size      int
+		separator string
+	}{
+		{"zeek", -1, ""},
+		{"zeek", 2, "x"},
+		{"zeek", 2, "1"},
+		{"ark:/12345/x5zeek", 2, "."},
+		{"ark:/12345/x5zeek", 2, " "},
+	} {
+		template, _ := NewTemplate(g.template)
+		template.GroupSize = g.size
+		template.GroupSeparator = g.separator
+		if _, err := NewTemplateMinter(template, 0); err == nil {
+			t.Errorf("Expected grouping %#v to be invalid", g)
+		}
+	}
+}
+
+func TestGroupingIsPersisted(t *testing.T) {
+	template, _ := NewTemplate("reeeeee")
+	template.GroupSize = 3
+	template.GroupSeparator = "~"
+	template.GroupFromRight = true
+	m, _ := NewTemplateMinter(template, 0)
+	m.Mint()
+
+	var buf bytes.Buffer
+	if err := m.WriteJSON(&buf); err != nil {
+		t.Fatalf("Unable to write minter: %s", err)
+	}
+	m2, err := NewMinterFromJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unable to read minter: %s", err)
+	}
+
+	assertEqualS(m.Mint(), m2.Mint(), "minting after a round trip", t)
+}
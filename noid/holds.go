@@ -0,0 +1,225 @@
+package noid
+
+// This file handles "holds": sequence values the minter must never use, such
+// as those belonging to identifiers which were assigned outside this minter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// SequenceRange is an inclusive range of sequence values
+type SequenceRange struct {
+	Start uint64
+	End   uint64
+}
+
+// sequenceSet stores sequence values as sorted, non-overlapping,
+// non-adjacent ranges, so large runs of values cost no more than one
+type sequenceSet []SequenceRange
+
+// Returns the index of the first range which ends at or after val
+func (s sequenceSet) search(val uint64) int {
+	return sort.Search(len(s), func(i int) bool { return s[i].End >= val })
+}
+
+func (s sequenceSet) contains(val uint64) bool {
+	i := s.search(val)
+	return i < len(s) && s[i].Start <= val
+}
+
+// Returns the first value at or after val which isn't in the set, or false if
+// every value from val through max is in the set
+func (s sequenceSet) nextFree(val, max uint64) (uint64, bool) {
+	i := s.search(val)
+	if i == len(s) || s[i].Start > val {
+		return val, true
+	}
+
+	// Ranges are never adjacent, so the value after this range is free
+	if s[i].End >= max {
+		return 0, false
+	}
+	return s[i].End + 1, true
+}
+
+// Adds all the given ranges to the set at once, which is far cheaper than
+// adding them one at a time
+func (s sequenceSet) add(ranges ...SequenceRange) sequenceSet {
+	all := append(append(sequenceSet(nil), s...), ranges...)
+	sort.Slice(all, func(i, j int) bool { return all[i].Start < all[j].Start })
+
+	var merged sequenceSet
+	for _, r := range all {
+		last := len(merged) - 1
+		if last >= 0 && (merged[last].End == ^uint64(0) || r.Start <= merged[last].End+1) {
+			if r.End > merged[last].End {
+				merged[last].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged
+}
+
+// Removes the given range from the set, splitting any range it falls inside
+func (s sequenceSet) remove(r SequenceRange) sequenceSet {
+	var result sequenceSet
+	for _, existing := range s {
+		if existing.End < r.Start || existing.Start > r.End {
+			result = append(result, existing)
+			continue
+		}
+		if existing.Start < r.Start {
+			result = append(result, SequenceRange{existing.Start, r.Start - 1})
+		}
+		if existing.End > r.End {
+			result = append(result, SequenceRange{r.End + 1, existing.End})
+		}
+	}
+
+	return result
+}
+
+func (r SequenceRange) validate(max uint64) error {
+	if r.Start > r.End {
+		return errors.New("Sequence range start must not be after its end")
+	}
+	if r.End > max {
+		return errors.New("Sequence range is outside the template's range")
+	}
+	return nil
+}
+
+// Holds are sequence values, which a dated template reuses in every date
+// bucket, so holding one noid would hold its counterpart in every other
+// bucket too
+var errDatedHolds = errors.New("Holds aren't supported for templates with date placeholders")
+
+func (m *Minter) validateHoldRange(r SequenceRange) error {
+	if m.template.dated() {
+		return errDatedHolds
+	}
+	return r.validate(m.generator.maxSequence)
+}
+
+// Returns the sequence value for each noid, as a range of one
+func (m *Minter) rangesForNoids(ids []string) ([]SequenceRange, error) {
+	if m.template.dated() {
+		return nil, errDatedHolds
+	}
+
+	ranges := make([]SequenceRange, len(ids))
+	for i, id := range ids {
+		seq, err := m.template.Decode(id)
+		if err != nil {
+			return nil, err
+		}
+		ranges[i] = SequenceRange{seq, seq}
+	}
+
+	return ranges, nil
+}
+
+// Returns true if the minter has already minted any sequence value in r
+func (m *Minter) anyMinted(r SequenceRange) bool {
+	end := r.End
+	if !m.exhausted {
+		next := m.generator.Sequence()
+		if next <= r.Start {
+			return false
+		}
+		if next-1 < end {
+			end = next - 1
+		}
+	}
+
+	minted := sequenceSet{{r.Start, end}}
+	for _, unminted := range [][]SequenceRange{m.holds, m.returned, m.skipped} {
+		for _, u := range unminted {
+			minted = minted.remove(u)
+		}
+	}
+	for _, candidates := range minted {
+		if _, ok := m.alignToShard(candidates.Start, candidates.End); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Hold marks the given noids as taken so the minter will never mint them.  If
+// any noid isn't valid for the minter's template, or has already been minted,
+// nothing is held.  Minters with dated templates can't hold noids.
+func (m *Minter) Hold(ids ...string) error {
+	ranges, err := m.rangesForNoids(ids)
+	if err != nil {
+		return err
+	}
+	for i, r := range ranges {
+		if m.anyMinted(r) {
+			return fmt.Errorf("%#v has already been minted", ids[i])
+		}
+	}
+
+	m.holds = m.holds.add(ranges...)
+	return nil
+}
+
+// HoldRange marks every sequence value from start to end, inclusive, as
+// taken.  Nothing is held if the minter has already minted any of them.
+func (m *Minter) HoldRange(start, end uint64) error {
+	r := SequenceRange{start, end}
+	if err := m.validateHoldRange(r); err != nil {
+		return err
+	}
+	if m.anyMinted(r) {
+		return fmt.Errorf("Range %d-%d includes noids which have already been minted", start, end)
+	}
+
+	m.holds = m.holds.add(r)
+	return nil
+}
+
+// Release removes the holds on the given noids
+func (m *Minter) Release(ids ...string) error {
+	ranges, err := m.rangesForNoids(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range ranges {
+		m.holds = m.holds.remove(r)
+	}
+	return nil
+}
+
+// ReleaseRange removes holds on every sequence value from start to end,
+// inclusive
+func (m *Minter) ReleaseRange(start, end uint64) error {
+	r := SequenceRange{start, end}
+	if err := m.validateHoldRange(r); err != nil {
+		return err
+	}
+
+	m.holds = m.holds.remove(r)
+	return nil
+}
+
+// Holds returns all held sequence values as a list of ranges
+func (m *Minter) Holds() []SequenceRange {
+	return append([]SequenceRange(nil), m.holds...)
+}
+
+// IsHeld returns true if the given noid's sequence value is held
+func (m *Minter) IsHeld(id string) (bool, error) {
+	seq, err := m.template.Decode(id)
+	if err != nil {
+		return false, err
+	}
+
+	return m.holds.contains(seq), nil
+}
@@ -0,0 +1,135 @@
+package noid
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSequenceSetMergesRanges(t *testing.T) {
+	var s sequenceSet
+	s = s.add(SequenceRange{5, 10}, SequenceRange{1, 2}, SequenceRange{11, 12}, SequenceRange{3, 3}, SequenceRange{20, 30})
+
+	expected := sequenceSet{{1, 3}, {5, 12}, {20, 30}}
+	if !reflect.DeepEqual(expected, s) {
+		t.Errorf("Expected %v, got %v", expected, s)
+	}
+
+	s = s.remove(SequenceRange{7, 8})
+	expected = sequenceSet{{1, 3}, {5, 6}, {9, 12}, {20, 30}}
+	if !reflect.DeepEqual(expected, s) {
+		t.Errorf("Expected %v after removal, got %v", expected, s)
+	}
+
+	if !s.contains(9) || s.contains(8) || s.contains(31) {
+		t.Errorf("Set %v has the wrong contents", s)
+	}
+}
+
+func TestSequenceSetHandlesMaxValue(t *testing.T) {
+	var s sequenceSet
+	max := ^uint64(0)
+	s = s.add(SequenceRange{max - 1, max}, SequenceRange{max, max})
+
+	if _, ok := s.nextFree(max-1, max); ok {
+		t.Errorf("Expected no free values at the top of the range")
+	}
+	v, _ := s.nextFree(max-2, max)
+	assertEqualUint64(max-2, v, "value below the held range is free", t)
+}
+
+func TestMintSkipsHeldNoids(t *testing.T) {
+	minter, _ := NewMinter("reedeek")
+	expected := make([]string, 10)
+	for i := range expected {
+		expected[i] = minter.Mint()
+	}
+
+	minter, _ = NewMinter("reedeek")
+	if err := minter.Hold(expected[1], expected[2], expected[5]); err != nil {
+		t.Fatalf("Unable to hold noids: %s", err)
+	}
+	minter.HoldRange(7, 8)
+
+	for _, i := range []int{0, 3, 4, 6, 9} {
+		assertEqualS(expected[i], minter.Mint(), "minting around holds", t)
+	}
+}
+
+func TestHoldRejectsInvalidNoids(t *testing.T) {
+	minter, _ := NewMinter("reedeek")
+	if err := minter.Hold(minter.Mint(), "bogus"); err == nil {
+		t.Errorf("Expected holding an invalid noid to be an error")
+	}
+	if len(minter.Holds()) != 0 {
+		t.Errorf("Expected nothing to be held after an error, got %v", minter.Holds())
+	}
+}
+
+func TestHoldRejectsMintedNoids(t *testing.T) {
+	minter, _ := NewMinter("x.sdd")
+	minter.HoldRange(1, 1)
+	first := minter.Mint()
+
+	if err := minter.Hold(first); err == nil {
+		t.Errorf("Expected holding a minted noid to be an error")
+	}
+	if err := minter.HoldRange(0, 5); err == nil {
+		t.Errorf("Expected holding a range with a minted noid to be an error")
+	}
+	if minted, _ := minter.Minted(first); !minted {
+		t.Errorf("Expected %#v to still be minted after failed holds", first)
+	}
+	if err := minter.SetStatus(first, Public, "", time.Now()); err != nil {
+		t.Errorf("Unable to set a minted noid's status after failed holds: %s", err)
+	}
+
+	// Values the minter skipped over, or hasn't reached, can still be held
+	if err := minter.HoldRange(1, 5); err != nil {
+		t.Errorf("Unable to hold unminted values: %s", err)
+	}
+	if err := minter.Hold("x.77"); err != nil {
+		t.Errorf("Unable to hold an unminted noid: %s", err)
+	}
+}
+
+func TestHoldingEverythingExhaustsMinter(t *testing.T) {
+	minter, _ := NewMinter("sdd")
+	minter.HoldRange(60, 63)
+	for i := 0; i < 60; i++ {
+		minter.Mint()
+	}
+
+	if minter.Exhausted() {
+		t.Errorf("Minter shouldn't be exhausted until it tries to mint a held value")
+	}
+	assertEqualS("", minter.Mint(), "minting when everything left is held", t)
+	if !minter.Exhausted() {
+		t.Errorf("Minter should be exhausted")
+	}
+}
+
+func TestMintingLastValueExhaustsMinter(t *testing.T) {
+	minter, _ := NewSequencedMinter("sdd", 63)
+	assertEqualS("77", minter.Mint(), "last noid", t)
+	assertEqualS("", minter.Mint(), "minting past the last noid", t)
+}
+
+func TestHoldsSurviveSerialization(t *testing.T) {
+	var buf bytes.Buffer
+
+	minter, _ := NewMinter("reedeek")
+	minter.HoldRange(0, 1000)
+	minter.ReleaseRange(10, 19)
+	minter.WriteJSON(&buf)
+
+	minter, err := NewMinterFromJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unable to read minter: %s", err)
+	}
+	expected := []SequenceRange{{0, 9}, {20, 1000}}
+	if !reflect.DeepEqual(expected, minter.Holds()) {
+		t.Errorf("Expected holds %v, got %v", expected, minter.Holds())
+	}
+}
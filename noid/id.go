@@ -0,0 +1,189 @@
+package noid
+
+// This file handles noids as typed, already-validated values rather than bare
+// strings
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// An ID is a noid which has already been validated against its template, so
+// its parts don't need parsing again.  IDs should come from Parse (or be
+// unmarshaled or scanned); the zero ID is "no noid", and is what a NULL
+// database column or JSON null becomes.
+type ID struct {
+	// Template is the template the noid belongs to
+	Template *Template
+
+	// Prefix is everything before the minted part: the prefix and separator,
+	// with any date placeholders filled in, or the ARK label, NAAN, and
+	// shoulder
+	Prefix string
+
+	// Suffix is the minted part, without group separators or check digit
+	Suffix string
+
+	// CheckDigit is the noid's check digit, or zero if it has none
+	CheckDigit rune
+
+	// Sequence is the value the template mints the noid from
+	Sequence uint64
+
+	canonical string
+}
+
+// Parse validates a noid against the template and splits it into its parts.
+// The noid is stored in its canonical form.
+func Parse(t *Template, s string) (ID, error) {
+	r, err := t.resolve(s)
+	if err != nil {
+		return ID{}, err
+	}
+	seq, err := r.Decode(s)
+	if err != nil {
+		return ID{}, err
+	}
+
+	suffix := NewSuffixGenerator(r, seq).ToString()
+	id := ID{Template: t, Prefix: r.literalHead(), Suffix: suffix, Sequence: seq, canonical: r.format(suffix)}
+	if r.HasCheckDigit {
+		body := []rune(r.body(id.canonical))
+		id.CheckDigit = body[len(body)-1]
+	}
+
+	return id, nil
+}
+
+// Parse validates a noid against the template.  See the package-level Parse.
+func (t *Template) Parse(s string) (ID, error) {
+	return Parse(t, s)
+}
+
+// Parse validates a noid against whichever of the minter's templates it
+// belongs to.  See the package-level Parse.
+func (m *Minter) Parse(s string) (ID, error) {
+	_, t, err := m.templateFor(s)
+	if err != nil {
+		return ID{}, err
+	}
+	return Parse(t, s)
+}
+
+// IsZero returns true for the zero ID
+func (id ID) IsZero() bool {
+	return id.canonical == ""
+}
+
+// String returns the noid's canonical form, or "" for the zero ID
+func (id ID) String() string {
+	return id.canonical
+}
+
+// Compare orders IDs by sequence, returning -1, 0, or 1.  IDs with the same
+// sequence, which can only happen with different templates or date buckets,
+// are ordered by their text so the ordering is still total.  The zero ID
+// comes before everything else.
+func (id ID) Compare(other ID) int {
+	switch {
+	case id.IsZero() != other.IsZero():
+		if id.IsZero() {
+			return -1
+		}
+		return 1
+	case id.Sequence < other.Sequence:
+		return -1
+	case id.Sequence > other.Sequence:
+		return 1
+	}
+	return strings.Compare(id.canonical, other.canonical)
+}
+
+// Parses s for unmarshaling and scanning: against the ID's template if it has
+// one, or else the most specific match in DefaultRegistry
+func (id *ID) parse(s string) error {
+	if id.Template != nil {
+		parsed, err := Parse(id.Template, s)
+		if err != nil {
+			return err
+		}
+		*id = parsed
+		return nil
+	}
+
+	matches := DefaultRegistry.Identify(s)
+	if len(matches) == 0 {
+		return fmt.Errorf("%#v isn't valid for any template in DefaultRegistry", s)
+	}
+	parsed, err := Parse(matches[0].Template, s)
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// MarshalText returns the noid's canonical form
+func (id ID) MarshalText() ([]byte, error) {
+	return []byte(id.canonical), nil
+}
+
+// UnmarshalText parses a noid.  Set Template first to parse against a
+// particular template; otherwise the noid must match a template registered in
+// DefaultRegistry.  Empty text becomes the zero ID, keeping any Template.
+func (id *ID) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*id = ID{Template: id.Template}
+		return nil
+	}
+	return id.parse(string(text))
+}
+
+// MarshalJSON returns the noid as a JSON string, or null for the zero ID
+func (id ID) MarshalJSON() ([]byte, error) {
+	if id.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(id.canonical)
+}
+
+// UnmarshalJSON parses a JSON string as UnmarshalText does.  null becomes
+// the zero ID.
+func (id *ID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*id = ID{Template: id.Template}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return id.UnmarshalText([]byte(s))
+}
+
+// Value stores the noid's canonical form in a database, or NULL for the zero
+// ID
+func (id ID) Value() (driver.Value, error) {
+	if id.IsZero() {
+		return nil, nil
+	}
+	return id.canonical, nil
+}
+
+// Scan reads a noid from a database column, validating it as UnmarshalText
+// does.  Only NULL becomes the zero ID; an empty string isn't a valid noid.
+func (id *ID) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*id = ID{Template: id.Template}
+		return nil
+	case string:
+		return id.parse(v)
+	case []byte:
+		return id.parse(string(v))
+	}
+	return fmt.Errorf("Can't scan %T into a noid; only text columns hold noids", src)
+}
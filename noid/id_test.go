@@ -0,0 +1,134 @@
+package noid
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseID(t *testing.T) {
+	template, _ := NewTemplate("x.reedeek")
+	template.GroupSize = 3
+	m, _ := NewTemplateMinter(template, 0)
+	minted := m.Mint()
+
+	id, err := Parse(template, minted)
+	if err != nil {
+		t.Fatalf("Unable to parse %#v: %s", minted, err)
+	}
+	assertEqualS(minted, id.String(), "ID's text", t)
+	assertEqualS("x.", id.Prefix, "ID's prefix", t)
+	assertEqualUint64(0, id.Sequence, "ID's sequence", t)
+
+	ungrouped := template.ungroup(minted[2:])
+	assertEqualS(ungrouped[:len(ungrouped)-1], id.Suffix, "ID's suffix", t)
+	assertEqualS(ungrouped[len(ungrouped)-1:], string(id.CheckDigit), "ID's check digit", t)
+
+	if _, err = template.Parse("x.nope"); err == nil {
+		t.Errorf("Expected an error parsing an invalid noid")
+	}
+}
+
+func TestParseIDForms(t *testing.T) {
+	ark, _ := NewTemplate("ark:/12345/x5reedeek")
+	id, err := ark.Parse("ark:12345/x5q67j4t")
+	if err != nil {
+		t.Fatalf("Unable to parse ARK: %s", err)
+	}
+	assertEqualS("ark:/12345/x5q67j4t", id.String(), "canonical ARK", t)
+	assertEqualS("ark:/12345/x5", id.Prefix, "ARK's prefix", t)
+	assertEqualS("q67j4", id.Suffix, "ARK's suffix", t)
+
+	dated, _ := NewTemplate("{YYYY}.sdd")
+	id, err = dated.Parse("2026.12")
+	if err != nil {
+		t.Fatalf("Unable to parse dated noid: %s", err)
+	}
+	assertEqualS("2026.", id.Prefix, "dated prefix", t)
+	assertEqualUint64(10, id.Sequence, "dated sequence", t)
+	if id.CheckDigit != 0 {
+		t.Errorf("Expected no check digit, got %#v", string(id.CheckDigit))
+	}
+}
+
+func TestCompareIDs(t *testing.T) {
+	template, _ := NewTemplate("rdd")
+	m, _ := NewTemplateMinter(template, 0)
+	a, _ := template.Parse(m.Mint())
+	b, _ := template.Parse(m.Mint())
+
+	if a.Compare(b) != -1 || b.Compare(a) != 1 || a.Compare(a) != 0 {
+		t.Errorf("Expected %s (%d) < %s (%d)", a, a.Sequence, b, b.Sequence)
+	}
+	if (ID{}).Compare(a) != -1 {
+		t.Errorf("Expected the zero ID to come first")
+	}
+}
+
+func TestIDJSON(t *testing.T) {
+	template, _ := NewTemplate("x.reedeek")
+	type record struct {
+		ID    ID
+		Other ID
+	}
+
+	id, _ := template.Parse("x.q67j4w")
+	data, err := json.Marshal(record{ID: id})
+	if err != nil {
+		t.Fatalf("Unable to marshal: %s", err)
+	}
+	assertEqualS(`{"ID":"x.q67j4w","Other":null}`, string(data), "JSON", t)
+
+	r := record{ID: ID{Template: template}}
+	if err = json.Unmarshal(data, &r); err != nil {
+		t.Fatalf("Unable to unmarshal: %s", err)
+	}
+	if r.ID.Compare(id) != 0 || !r.Other.IsZero() {
+		t.Errorf("Expected %v back, got %v", id, r)
+	}
+
+	r = record{ID: ID{Template: template}}
+	if err = json.Unmarshal([]byte(`{"ID":"x.q67j4x"}`), &r); err == nil {
+		t.Errorf("Expected an error unmarshaling a noid with a bad check digit")
+	}
+}
+
+func TestIDSQL(t *testing.T) {
+	template, _ := NewTemplate("x.reedeek")
+	id, _ := template.Parse("x.q67j4w")
+
+	v, _ := id.Value()
+	assertEqualS("x.q67j4w", v.(string), "database value", t)
+	if v, _ = (ID{}).Value(); v != nil {
+		t.Errorf("Expected NULL for the zero ID, got %#v", v)
+	}
+
+	// Without a template, the ID looks one up in the default registry
+	var scanned ID
+	if err := scanned.Scan([]byte("x.q67j4w")); err == nil {
+		t.Errorf("Expected an error scanning with no registered templates")
+	}
+	DefaultRegistry.Register("test", template)
+	defer func() { DefaultRegistry = NewRegistry() }()
+
+	if err := scanned.Scan([]byte("x.q67j4w")); err != nil {
+		t.Fatalf("Unable to scan: %s", err)
+	}
+	assertEqualS("x.q67j4w", scanned.String(), "scanned ID", t)
+	if err := scanned.Scan(nil); err != nil || !scanned.IsZero() {
+		t.Errorf("Expected NULL to scan as the zero ID")
+	}
+	if err := scanned.Scan(42); err == nil {
+		t.Errorf("Expected an error scanning a number")
+	}
+
+	// Only NULL is the zero ID; empty text is just an invalid noid
+	for _, empty := range []interface{}{"", []byte{}} {
+		if err := scanned.Scan(empty); err == nil {
+			t.Errorf("Expected an error scanning %#v", empty)
+		}
+	}
+	withTemplate := ID{Template: template}
+	if err := withTemplate.Scan(""); err == nil {
+		t.Errorf("Expected an error scanning an empty string with a template")
+	}
+}
@@ -0,0 +1,326 @@
+package noid
+
+// This file handles leasing ranges of a minter's sequence to clients which
+// mint while disconnected, such as laptops in the field
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// A Lease is a contiguous range of sequence values handed to a named client.
+// Only the client mints from the range until it's returned or expires.
+// Generation is the granting minter's generation at the time, since the range
+// only means something for that template; see Minter.Generation.
+type Lease struct {
+	Client     string
+	Start      uint64
+	End        uint64
+	Expires    time.Time
+	Generation int  `json:",omitempty"`
+	Closed     bool `json:",omitempty"`
+}
+
+// ErrLeaseExpired is returned when a client tries to mint after its lease has
+// expired
+var ErrLeaseExpired = errors.New("Lease has expired")
+
+// ErrLeaseClosed is returned when a client tries to mint after its lease has
+// been closed for return
+var ErrLeaseClosed = errors.New("Lease has been closed")
+
+// Returns true if the lease has expired as of the given time
+func (l *Lease) expiredAt(now time.Time) bool {
+	return !now.Before(l.Expires)
+}
+
+// Returns the reason a client minter can't mint from its lease, or nil if it
+// can (or isn't a client at all)
+func (m *Minter) leaseError() error {
+	switch {
+	case m.lease == nil:
+		return nil
+	case m.lease.Closed:
+		return ErrLeaseClosed
+	case m.lease.expiredAt(m.now()):
+		return ErrLeaseExpired
+	}
+	return nil
+}
+
+// GrantLease reserves count sequence values for the named client, good until
+// expires, and returns a minter which mints only from that range.  The
+// client's minter is meant to be written to a lease file and carried off;
+// this minter never mints from the range unless the client returns part of
+// it.  Ranges returned by earlier leases are reused before the minter's
+// sequence moves on.  For sharded minters, only the shard's values count
+// toward count.
+//
+// Held values in the range are skipped by the client just as they would be
+// here, so a lease may hold fewer than count noids.  Dated templates can't be
+// leased, since the client can't know which bucket it will be minting in.
+func (m *Minter) GrantLease(client string, count uint64, expires time.Time) (*Minter, error) {
+	if m.lease != nil {
+		return nil, errors.New("Leased minters can't grant leases of their own")
+	}
+	if m.template.dated() {
+		return nil, errors.New("Templates with date placeholders can't be leased")
+	}
+	if client == "" {
+		return nil, errors.New("Leases must name a client")
+	}
+	if count == 0 {
+		return nil, errors.New("Leases must have at least one sequence value")
+	}
+	if !expires.After(m.now()) {
+		return nil, errors.New("Lease expiry must be in the future")
+	}
+	for _, l := range m.leases {
+		if l.Client == client {
+			return nil, fmt.Errorf("Client %#v already has a lease", client)
+		}
+	}
+
+	// A span of count shard values, less one, so it can be added to a start
+	// value without overflowing
+	stride := m.Shard().Count
+	if (count - 1) > ^uint64(0)/stride {
+		return nil, errors.New("Lease is too big for the template")
+	}
+	span := (count - 1) * stride
+
+	r, ok := m.leaseReturned(span)
+	if !ok {
+		var err error
+		r, err = m.leaseSequence(span)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	l := Lease{Client: client, Start: r.Start, End: r.End, Expires: expires, Generation: len(m.generations)}
+	m.leases = append(append([]Lease(nil), m.leases...), l)
+	cl := l
+
+	c := &Minter{
+		template:  m.template,
+		generator: NewSuffixGenerator(m.template, l.Start),
+		holds:     m.holds,
+		blocklist: *m.blocklist.clone(),
+		shard:     m.shard,
+		lease:     &cl,
+		clock:     m.clock,
+	}
+	return c, nil
+}
+
+// Looks for a returned range with room for the given span of the minter's
+// shard, removing it from the returned set if one is found
+func (m *Minter) leaseReturned(span uint64) (SequenceRange, bool) {
+	for _, free := range m.returned {
+		start, ok := m.alignToShard(free.Start, free.End)
+		if !ok || free.End-start < span {
+			continue
+		}
+
+		r := SequenceRange{start, start + span}
+		m.returned = m.returned.remove(r)
+		return r, true
+	}
+
+	return SequenceRange{}, false
+}
+
+// Takes a span of values from the minter's current sequence, moving the
+// sequence past it
+func (m *Minter) leaseSequence(span uint64) (SequenceRange, error) {
+	for !m.skipHeld() {
+		if !m.rollTemplate() {
+			return SequenceRange{}, ErrExhausted
+		}
+	}
+
+	g := m.generator
+	if g.maxSequence-g.sequenceValue < span {
+		return SequenceRange{}, errors.New("Not enough sequence values left for the lease")
+	}
+	r := SequenceRange{g.sequenceValue, g.sequenceValue + span}
+
+	g.sequenceValue = r.End
+	if m.nextSequence() != nil {
+		m.exhausted = true
+	}
+	return r, nil
+}
+
+// Lease returns the lease a client minter mints from, or nil if the minter
+// isn't a client
+func (m *Minter) Lease() *Lease {
+	if m.lease == nil {
+		return nil
+	}
+	l := *m.lease
+	return &l
+}
+
+// Leases returns the leases the minter has granted which haven't been
+// returned or expired
+func (m *Minter) Leases() []Lease {
+	return append([]Lease(nil), m.leases...)
+}
+
+// Returned returns the ranges clients have handed back which haven't been
+// minted or leased again
+func (m *Minter) Returned() []SequenceRange {
+	return append([]SequenceRange(nil), m.returned...)
+}
+
+// ReturnLease takes back whatever part of a client's lease it hasn't minted,
+// so this minter (or a future lease) can use it.  Data bound to the client's
+// noids, and their statuses, are copied here as well.
+//
+// The client must never mint from the lease again, so its lease has to be
+// closed first.  Save the closed client before saving this minter, so a crash
+// in between can only waste the remainder rather than hand it out twice.
+func (m *Minter) ReturnLease(c *Minter) error {
+	if c.lease == nil {
+		return errors.New("Minter isn't a leased client")
+	}
+	if !c.lease.Closed {
+		return errors.New("Leases must be closed before they're returned")
+	}
+	if !m.grantedFrom(c) {
+		return errors.New("Lease was granted by a minter with a different template")
+	}
+
+	i := m.findLease(*c.lease)
+	if i == -1 {
+		return fmt.Errorf("Client %#v has no active lease for sequence values %d-%d", c.lease.Client, c.lease.Start, c.lease.End)
+	}
+
+	// A client can only have skipped values in its own lease; anything else
+	// means its state was damaged or edited
+	for _, r := range c.skipped {
+		if r.Start < c.lease.Start || r.End > c.lease.End {
+			return fmt.Errorf("Client skipped sequence values %d-%d, outside its lease of %d-%d", r.Start, r.End, c.lease.Start, c.lease.End)
+		}
+	}
+
+	for id, b := range c.bindings {
+		if err := m.Bind(id, b); err != nil {
+			return err
+		}
+	}
+	if len(c.statuses) > 0 {
+		statuses := make(map[string]*Lifecycle, len(m.statuses)+len(c.statuses))
+		for id, l := range m.statuses {
+			statuses[id] = l
+		}
+		for id, l := range c.statuses {
+			statuses[id] = l
+		}
+		m.statuses = statuses
+	}
+
+	// What's left of leases from templates the minter has since rolled over
+	// from is never minted, as there's no going back to those templates, but
+	// it's remembered so those noids aren't mistaken for minted ones
+	var unused sequenceSet
+	if next := c.generator.Sequence(); !c.exhausted && next <= c.lease.End {
+		unused = unused.add(SequenceRange{next, c.lease.End})
+	}
+	if gen := c.lease.Generation; gen == len(m.generations) {
+		m.returned = m.returned.add(unused...)
+		m.skipped = m.skipped.add(c.skipped...)
+	} else {
+		gens := append([]generation(nil), m.generations...)
+		gens[gen].returned = gens[gen].returned.add(unused...)
+		gens[gen].skipped = gens[gen].skipped.add(c.skipped...)
+		m.generations = gens
+	}
+	m.leases = append(append([]Lease(nil), m.leases[:i]...), m.leases[i+1:]...)
+
+	return nil
+}
+
+// Returns true if the client's lease could have come from this minter
+func (m *Minter) grantedFrom(c *Minter) bool {
+	gens := m.Generations()
+	gen := c.lease.Generation
+	return c.Shard() == m.Shard() && gen >= 0 && gen < len(gens) && gens[gen].sameAs(c.template)
+}
+
+// Returns the index of the given lease, or -1 if it isn't active
+func (m *Minter) findLease(l Lease) int {
+	for i, active := range m.leases {
+		if active.Client == l.Client && active.Start == l.Start && active.End == l.End && active.Generation == l.Generation {
+			return i
+		}
+	}
+	return -1
+}
+
+// CloseLease stops a client minter from minting any more noids, which must be
+// done before its lease is returned
+func (m *Minter) CloseLease() error {
+	if m.lease == nil {
+		return errors.New("Minter isn't a leased client")
+	}
+	l := *m.lease
+	l.Closed = true
+	m.lease = &l
+	return nil
+}
+
+// ExpireLeases drops every lease whose expiry has passed, returning them.
+// Expired clients refuse to mint, but there's no telling how much of a lease
+// was used before then, so the whole range stays out of circulation.
+func (m *Minter) ExpireLeases() []Lease {
+	now := m.now()
+	var kept, expired []Lease
+	for _, l := range m.leases {
+		if l.expiredAt(now) {
+			expired = append(expired, l)
+		} else {
+			kept = append(kept, l)
+		}
+	}
+
+	m.leases = kept
+	return expired
+}
+
+// Takes the lowest returned value this minter can mint, if any
+func (m *Minter) mintReturned() string {
+	for len(m.returned) > 0 {
+		seq, ok := m.alignToShard(m.returned[0].Start, m.returned[0].End)
+		if !ok {
+			m.returned = m.returned[1:]
+			continue
+		}
+		m.returned = m.returned.remove(SequenceRange{m.returned[0].Start, seq})
+
+		if m.holds.contains(seq) {
+			continue
+		}
+		g := *m.generator
+		g.sequenceValue = seq
+		id := m.template.format(g.ToString())
+		if !m.blocklist.blocks(m.template, id) {
+			return id
+		}
+		m.skipped = m.skipped.add(SequenceRange{seq, seq})
+	}
+
+	return ""
+}
+
+// Returns the largest sequence value the minter may use: the end of its lease
+// for clients, or the template's range otherwise
+func (m *Minter) maxSequence() uint64 {
+	if m.lease != nil {
+		return m.lease.End
+	}
+	return m.generator.maxSequence
+}
@@ -0,0 +1,265 @@
+package noid
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func newLeaseMinter(t *testing.T, template string) (*Minter, *fakeClock) {
+	clock := &fakeClock{time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC)}
+	m, err := NewMinter(template)
+	if err != nil {
+		t.Fatalf("Unable to create minter: %s", err)
+	}
+	m.SetClock(clock.Now)
+	return m, clock
+}
+
+// Mints everything a minter has left, failing if any noid was already seen
+func mintAllUnique(m *Minter, seen map[string]string, who string, t *testing.T) {
+	for id := m.Mint(); id != ""; id = m.Mint() {
+		if other, ok := seen[id]; ok {
+			t.Fatalf("%s and %s both minted %#v", other, who, id)
+		}
+		seen[id] = who
+	}
+}
+
+func TestLeasesDontOverlap(t *testing.T) {
+	m, clock := newLeaseMinter(t, "sdd")
+	expires := clock.now.Add(time.Hour)
+
+	a, err := m.GrantLease("laptop-a", 10, expires)
+	if err != nil {
+		t.Fatalf("Unable to grant lease: %s", err)
+	}
+	first := m.Mint()
+	b, err := m.GrantLease("laptop-b", 25, expires)
+	if err != nil {
+		t.Fatalf("Unable to grant lease: %s", err)
+	}
+
+	assertEqualUint64(0, a.Lease().Start, "first lease start", t)
+	assertEqualUint64(9, a.Lease().End, "first lease end", t)
+	assertEqualUint64(11, b.Lease().Start, "second lease start", t)
+	assertEqualUint64(35, b.Lease().End, "second lease end", t)
+	assertEqualUint64(36, m.Sequence(), "sequence after leasing", t)
+
+	seen := map[string]string{first: "the minter"}
+	mintAllUnique(a, seen, "laptop-a", t)
+	mintAllUnique(b, seen, "laptop-b", t)
+	mintAllUnique(m, seen, "the minter", t)
+	if len(seen) != 64 {
+		t.Errorf("Expected 64 noids in all, got %d", len(seen))
+	}
+}
+
+func TestReturnedLeasesAreReused(t *testing.T) {
+	m, clock := newLeaseMinter(t, "sdd")
+	c, _ := m.GrantLease("laptop", 10, clock.now.Add(time.Hour))
+	assertEqualS("00", c.Mint(), "first leased noid", t)
+	assertEqualS("01", c.Mint(), "second leased noid", t)
+	c.Bind("01", Bindings{"who": "field team"})
+
+	if err := m.ReturnLease(c); err == nil {
+		t.Errorf("Expected an error returning a lease which isn't closed")
+	}
+	c.CloseLease()
+	assertEqualS("", c.Mint(), "minting from a closed lease", t)
+	if _, err := c.MintAndBind(nil); err != ErrLeaseClosed {
+		t.Errorf("Expected ErrLeaseClosed, got %v", err)
+	}
+
+	if err := m.ReturnLease(c); err != nil {
+		t.Fatalf("Unable to return lease: %s", err)
+	}
+	if err := m.ReturnLease(c); err == nil {
+		t.Errorf("Expected an error returning a lease twice")
+	}
+	if len(m.Leases()) != 0 {
+		t.Errorf("Expected no active leases, got %v", m.Leases())
+	}
+	assertEqualS("field team", m.Bindings("01")["who"], "binding copied from the client", t)
+
+	// Part of the remainder goes to a new lease, and the minter mints the rest
+	// before moving on
+	c, _ = m.GrantLease("laptop", 3, clock.now.Add(time.Hour))
+	assertEqualUint64(2, c.Lease().Start, "lease from returned range", t)
+	expected := []string{"05", "06", "07", "10", "11", "12"}
+	for _, id := range expected {
+		assertEqualS(id, m.Mint(), "minting after a return", t)
+	}
+
+	minted, _ := m.Minted("11")
+	if !minted {
+		t.Errorf("Expected 11 to be minted")
+	}
+}
+
+func TestExpiredLeases(t *testing.T) {
+	m, clock := newLeaseMinter(t, "sdd")
+	c, _ := m.GrantLease("laptop", 10, clock.now.Add(time.Hour))
+	m.GrantLease("desktop", 10, clock.now.Add(3*time.Hour))
+	assertEqualS("00", c.Mint(), "minting before expiry", t)
+
+	clock.now = clock.now.Add(2 * time.Hour)
+	assertEqualS("", c.Mint(), "minting after expiry", t)
+	if _, err := c.MintAndBind(nil); err != ErrLeaseExpired {
+		t.Errorf("Expected ErrLeaseExpired, got %v", err)
+	}
+
+	expired := m.ExpireLeases()
+	if len(expired) != 1 || expired[0].Client != "laptop" {
+		t.Errorf("Expected only laptop's lease to expire, got %v", expired)
+	}
+	if len(m.Leases()) != 1 {
+		t.Errorf("Expected one active lease, got %v", m.Leases())
+	}
+
+	// Expired ranges are never reused, since the client may have minted any
+	// part of them
+	c.CloseLease()
+	if err := m.ReturnLease(c); err == nil {
+		t.Errorf("Expected an error returning an expired lease")
+	}
+	assertEqualS("24", m.Mint(), "minting after expiry", t)
+}
+
+func TestGrantLeaseErrors(t *testing.T) {
+	m, clock := newLeaseMinter(t, "sdd")
+	later := clock.now.Add(time.Hour)
+
+	if _, err := m.GrantLease("", 10, later); err == nil {
+		t.Errorf("Expected an error for a lease with no client")
+	}
+	if _, err := m.GrantLease("laptop", 0, later); err == nil {
+		t.Errorf("Expected an error for an empty lease")
+	}
+	if _, err := m.GrantLease("laptop", 10, clock.now); err == nil {
+		t.Errorf("Expected an error for a lease which has already expired")
+	}
+	if _, err := m.GrantLease("laptop", 65, later); err == nil {
+		t.Errorf("Expected an error for a lease bigger than the template")
+	}
+
+	c, _ := m.GrantLease("laptop", 10, later)
+	if _, err := m.GrantLease("laptop", 10, later); err == nil {
+		t.Errorf("Expected an error for a client with two leases")
+	}
+	if _, err := c.GrantLease("tablet", 1, later); err == nil {
+		t.Errorf("Expected an error for a client granting a lease")
+	}
+
+	dated, _ := NewMinter("{YYYY}.sdd")
+	if _, err := dated.GrantLease("laptop", 10, later); err == nil {
+		t.Errorf("Expected an error leasing a dated template")
+	}
+}
+
+func TestReturnLeaseChecksCheckDigit(t *testing.T) {
+	ncda, _ := NewTemplate("x.sdddk")
+	ncda.CheckDigit = NCDA
+	m, _ := NewTemplateMinter(ncda, 0)
+	c, _ := m.GrantLease("laptop", 3, time.Now().Add(time.Hour))
+	c.CloseLease()
+
+	// Same template string, same lease, different check digits
+	damm, _ := NewTemplate("x.sdddk")
+	damm.CheckDigit = Damm
+	other, _ := NewTemplateMinter(damm, 0)
+	other.leases = m.leases
+	if err := other.ReturnLease(c); err == nil {
+		t.Errorf("Expected an error returning a lease to a minter with a different check digit")
+	}
+	if err := m.ReturnLease(c); err != nil {
+		t.Errorf("Unable to return lease: %s", err)
+	}
+}
+
+func TestReturnLeaseChecksSkippedRanges(t *testing.T) {
+	m, clock := newLeaseMinter(t, "sdd")
+	m.Block("03")
+	c, _ := m.GrantLease("laptop", 5, clock.now.Add(time.Hour))
+	for c.Mint() != "" {
+	}
+	c.CloseLease()
+
+	// A hand-edited client state claiming to have skipped values outside its
+	// lease is turned away, and nothing from it is kept
+	edited := *c
+	edited.skipped = edited.skipped.add(SequenceRange{20, 30})
+	if err := m.ReturnLease(&edited); err == nil {
+		t.Errorf("Expected an error returning a lease with skips outside it")
+	}
+	if minted, _ := m.Minted("03"); !minted {
+		t.Errorf("Expected nothing to change after a rejected return")
+	}
+
+	if err := m.ReturnLease(c); err != nil {
+		t.Fatalf("Unable to return lease: %s", err)
+	}
+	for _, tc := range []struct {
+		id     string
+		minted bool
+	}{{"02", true}, {"03", false}, {"04", true}} {
+		if minted, _ := m.Minted(tc.id); minted != tc.minted {
+			t.Errorf("Expected %#v to have minted %v, got %v", tc.id, tc.minted, minted)
+		}
+	}
+}
+
+func TestShardedLeases(t *testing.T) {
+	template, _ := NewTemplate("sdd")
+	m, _ := NewShardedMinter(template, 1, 4)
+	c, err := m.GrantLease("laptop", 3, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Unable to grant lease: %s", err)
+	}
+
+	assertEqualS("01", c.Mint(), "first leased noid", t)
+	assertEqualS("05", c.Mint(), "second leased noid", t)
+	assertEqualS("11", c.Mint(), "third leased noid", t)
+	assertEqualS("", c.Mint(), "leased noid past the lease", t)
+	assertEqualS("15", m.Mint(), "minter's noid after the lease", t)
+}
+
+func TestLeasesSerialize(t *testing.T) {
+	m, clock := newLeaseMinter(t, "sdd")
+	c, _ := m.GrantLease("laptop", 10, clock.now.Add(time.Hour))
+	c.Mint()
+
+	var buf bytes.Buffer
+	c.WriteJSON(&buf)
+	c2, err := NewMinterFromJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unable to read client: %s", err)
+	}
+	c2.SetClock(clock.Now)
+	assertEqualS("01", c2.Mint(), "client after reloading", t)
+	c2.CloseLease()
+
+	buf.Reset()
+	c2.WriteJSON(&buf)
+	c3, _ := NewMinterFromJSON(&buf)
+	assertEqualS("", c3.Mint(), "closed client after reloading", t)
+
+	m.ReturnLease(c3)
+	m.GrantLease("desktop", 5, clock.now.Add(time.Hour))
+	buf.Reset()
+	m.WriteJSON(&buf)
+	m2, err := NewMinterFromJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unable to read minter: %s", err)
+	}
+	if len(m2.Leases()) != 1 || m2.Leases()[0].Client != "desktop" {
+		t.Errorf("Expected desktop's lease, got %v", m2.Leases())
+	}
+	assertEqualS("07", m2.Mint(), "minter after reloading", t)
+
+	// A lease beyond the minter's sequence could overlap what it mints later
+	bad := `{"Template":"sdd","Sequence":5,"Leases":[{"Client":"x","Start":3,"End":8}]}`
+	if _, err = NewMinterFromJSON(bytes.NewBufferString(bad)); err == nil {
+		t.Errorf("Expected an error for a lease the minter hasn't reached")
+	}
+}
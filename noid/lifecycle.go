@@ -0,0 +1,169 @@
+package noid
+
+// This file handles tracking the lifecycle status of minted noids
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Status is where an identifier is in its lifecycle.  Every minted noid starts
+// out Reserved.
+type Status int
+
+const (
+	Reserved Status = iota
+	Public
+	Unavailable
+	Withdrawn
+)
+
+var statusNames = map[Status]string{
+	Reserved:    "reserved",
+	Public:      "public",
+	Unavailable: "unavailable",
+	Withdrawn:   "withdrawn",
+}
+
+// Lists the statuses each status may move to.  Nothing goes back to reserved,
+// and withdrawn is permanent.
+var statusTransitions = map[Status][]Status{
+	Reserved:    {Public, Unavailable, Withdrawn},
+	Public:      {Unavailable, Withdrawn},
+	Unavailable: {Public, Withdrawn},
+}
+
+func (s Status) String() string {
+	name, ok := statusNames[s]
+	if !ok {
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+	return name
+}
+
+// ParseStatus returns the status with the given name, e.g., "public"
+func ParseStatus(name string) (Status, error) {
+	for s, n := range statusNames {
+		if n == name {
+			return s, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown status %#v", name)
+}
+
+func (s Status) MarshalText() ([]byte, error) {
+	if _, ok := statusNames[s]; !ok {
+		return nil, fmt.Errorf("Unknown status %d", int(s))
+	}
+	return []byte(s.String()), nil
+}
+
+func (s *Status) UnmarshalText(text []byte) error {
+	var err error
+	*s, err = ParseStatus(string(text))
+	return err
+}
+
+// CanChangeTo returns true if an identifier with status s may be given the
+// new status
+func (s Status) CanChangeTo(newStatus Status) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == newStatus {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusChange records a single transition in an identifier's history
+type StatusChange struct {
+	From   Status
+	To     Status
+	Time   time.Time
+	Reason string `json:",omitempty"`
+}
+
+// Lifecycle is the current status of an identifier and how it got there.
+// Reason is set for unavailable and withdrawn identifiers, and Tombstone is
+// the time an identifier was withdrawn.
+type Lifecycle struct {
+	Status    Status
+	Reason    string     `json:",omitempty"`
+	Tombstone *time.Time `json:",omitempty"`
+	History   []StatusChange
+}
+
+func (l *Lifecycle) clone() *Lifecycle {
+	c := *l
+	c.History = append([]StatusChange(nil), l.History...)
+	if l.Tombstone != nil {
+		ts := *l.Tombstone
+		c.Tombstone = &ts
+	}
+	return &c
+}
+
+// Status returns the lifecycle of the given noid, which must already have
+// been minted.  Minted noids which have never had their status changed are
+// reserved, with no history.
+func (m *Minter) Status(id string) (*Lifecycle, error) {
+	id, err := m.Canonical(id)
+	if err != nil {
+		return nil, err
+	}
+
+	minted, err := m.Minted(id)
+	if err != nil {
+		return nil, err
+	}
+	if !minted {
+		return nil, fmt.Errorf("%#v hasn't been minted", id)
+	}
+
+	l := m.statuses[id]
+	if l == nil {
+		return &Lifecycle{Status: Reserved}, nil
+	}
+	return l.clone(), nil
+}
+
+// SetStatus moves the given noid to a new status as of the given time.  The
+// noid must already have been minted.  Withdrawing a noid requires a reason;
+// the reason is optional when marking a noid unavailable, and ignored
+// otherwise.
+func (m *Minter) SetStatus(id string, s Status, reason string, at time.Time) error {
+	id, err := m.Canonical(id)
+	if err != nil {
+		return err
+	}
+
+	l, err := m.Status(id)
+	if err != nil {
+		return err
+	}
+
+	if !l.Status.CanChangeTo(s) {
+		return fmt.Errorf("%#v cannot go from %s to %s", id, l.Status, s)
+	}
+	if s == Withdrawn && reason == "" {
+		return errors.New("Withdrawing an identifier requires a reason")
+	}
+	if s != Unavailable && s != Withdrawn {
+		reason = ""
+	}
+
+	l.History = append(l.History, StatusChange{From: l.Status, To: s, Time: at, Reason: reason})
+	l.Status = s
+	l.Reason = reason
+	if s == Withdrawn {
+		l.Tombstone = &at
+	}
+
+	if m.statuses == nil {
+		m.statuses = make(map[string]*Lifecycle)
+	}
+	m.statuses[id] = l
+	return nil
+}
@@ -0,0 +1,117 @@
+package noid
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestNewNoidsAreReserved(t *testing.T) {
+	minter, _ := NewMinter("reedeek")
+	l, err := minter.Status(minter.Mint())
+	if err != nil {
+		t.Fatalf("Unexpected error getting status: %s", err)
+	}
+	if l.Status != Reserved {
+		t.Errorf("Expected a new noid to be reserved, got %s", l.Status)
+	}
+	if len(l.History) != 0 {
+		t.Errorf("Expected a new noid to have no history, got %#v", l.History)
+	}
+}
+
+func TestStatusRequiresValidNoid(t *testing.T) {
+	minter, _ := NewMinter("reedeek")
+	if _, err := minter.Status("nope"); err == nil {
+		t.Errorf("Expected an invalid noid to be an error")
+	}
+	if err := minter.SetStatus("nope", Public, "", time.Now()); err == nil {
+		t.Errorf("Expected setting an invalid noid's status to be an error")
+	}
+}
+
+func TestStatusRequiresMintedNoid(t *testing.T) {
+	minter, _ := NewMinter("reedeek")
+	ids := minter.Peek(2)
+	minter.Hold(ids[1])
+
+	if err := minter.SetStatus(ids[0], Public, "", time.Now()); err == nil {
+		t.Errorf("Expected setting an unminted noid's status to be an error")
+	}
+	if _, err := minter.Status(ids[0]); err == nil {
+		t.Errorf("Expected getting an unminted noid's status to be an error")
+	}
+	minter.Mint()
+	if _, err := minter.Status(ids[0]); err != nil {
+		t.Errorf("Unable to get a minted noid's status: %s", err)
+	}
+	if err := minter.SetStatus(ids[0], Public, "", time.Now()); err != nil {
+		t.Errorf("Unable to set a minted noid's status: %s", err)
+	}
+
+	minter.Mint()
+	if err := minter.SetStatus(ids[1], Public, "", time.Now()); err == nil {
+		t.Errorf("Expected setting a held noid's status to be an error")
+	}
+	if _, err := minter.Status(ids[1]); err == nil {
+		t.Errorf("Expected getting a held noid's status to be an error")
+	}
+}
+
+func TestStatusTransitions(t *testing.T) {
+	minter, _ := NewMinter("reedeek")
+	id := minter.Mint()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	if err := minter.SetStatus(id, Public, "", now); err != nil {
+		t.Fatalf("reserved -> public: %s", err)
+	}
+	if err := minter.SetStatus(id, Reserved, "", now); err == nil {
+		t.Errorf("Expected public -> reserved to be an error")
+	}
+	if err := minter.SetStatus(id, Unavailable, "Server maintenance", now); err != nil {
+		t.Fatalf("public -> unavailable: %s", err)
+	}
+	if err := minter.SetStatus(id, Withdrawn, "", now); err == nil {
+		t.Errorf("Expected withdrawing without a reason to be an error")
+	}
+	if err := minter.SetStatus(id, Withdrawn, "Duplicate record", now); err != nil {
+		t.Fatalf("unavailable -> withdrawn: %s", err)
+	}
+	if err := minter.SetStatus(id, Public, "", now); err == nil {
+		t.Errorf("Expected withdrawn -> public to be an error")
+	}
+
+	l, _ := minter.Status(id)
+	if l.Status != Withdrawn {
+		t.Errorf("Expected status to be withdrawn, got %s", l.Status)
+	}
+	assertEqualS("Duplicate record", l.Reason, "withdrawn reason", t)
+	if l.Tombstone == nil || !l.Tombstone.Equal(now) {
+		t.Errorf("Expected tombstone date %s, got %v", now, l.Tombstone)
+	}
+	if len(l.History) != 3 {
+		t.Fatalf("Expected 3 history entries, got %d", len(l.History))
+	}
+	if l.History[1].From != Public || l.History[1].To != Unavailable {
+		t.Errorf("Expected second history entry to be public -> unavailable, got %#v", l.History[1])
+	}
+}
+
+func TestStatusSurvivesSerialization(t *testing.T) {
+	var buf bytes.Buffer
+
+	minter, _ := NewMinter("reedeek")
+	id := minter.Mint()
+	minter.SetStatus(id, Public, "", time.Now())
+	minter.WriteJSON(&buf)
+
+	minter, err := NewMinterFromJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unable to read minter: %s", err)
+	}
+	l, _ := minter.Status(id)
+	if l.Status != Public {
+		t.Errorf("Expected status to be public after a round trip, got %s", l.Status)
+	}
+}
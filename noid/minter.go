@@ -2,14 +2,44 @@ package noid
 
 import (
 	"errors"
-	"strings"
+	"time"
 )
 
 type Minter struct {
 	template  *Template
 	generator *SuffixGenerator
+	bindings  map[string]Bindings
+	statuses  map[string]*Lifecycle
+	holds     sequenceSet
+	blocklist Blocklist
+	shard     Shard
+	exhausted bool
+
+	// Sequence values Mint passed over because they were blocked at the time.
+	// Like the generator, this is for the current template and bucket.
+	skipped sequenceSet
+
+	// Dated templates keep a sequence per bucket: the generator and exhausted
+	// flag are for the current bucket, and buckets holds the rest
+	bucket  string
+	buckets map[string]BucketState
+	clock   func() time.Time
+
+	// Minters granting leases track them along with any ranges clients have
+	// handed back; a leased client's minter holds the lease it mints from
+	leases   []Lease
+	returned sequenceSet
+	lease    *Lease
+
+	// Templates the minter has used up, oldest first, and the ones it will
+	// switch to when the current template runs out
+	generations []generation
+	rollover    []*Template
 }
 
+// ErrExhausted is returned when a minter has no more noids to give
+var ErrExhausted = errors.New("Minter is exhausted")
+
 func NewMinter(template string) (*Minter, error) {
 	return NewSequencedMinter(template, 0)
 }
@@ -19,13 +49,39 @@ func NewSequencedMinter(template string, startSequence uint64) (*Minter, error)
 	if err != nil {
 		return nil, err
 	}
+
+	return NewTemplateMinter(t, startSequence)
+}
+
+// NewTemplateMinter returns a minter for an already-parsed template, which
+// allows for template settings the template string can't hold, such as the
+// check digit algorithm.  The template is copied, so changing it afterward
+// has no effect on the minter.
+func NewTemplateMinter(template *Template, startSequence uint64) (*Minter, error) {
+	copied := *template
+	t := &copied
+
+	bits, err := t.maskBits()
+	if err != nil {
+		return nil, err
+	}
+	if err = t.validateGrouping(); err != nil {
+		return nil, err
+	}
+	if err = t.validateCheckDigit(); err != nil {
+		return nil, err
+	}
+	if bits > 64 {
+		return nil, errors.New("Template range is too big!  Try a shorter template mask string.")
+	}
+	if t.Ordering == Random && bits < 3 {
+		return nil, errors.New("Random templates need a mask of at least three bits")
+	}
+
 	g := NewSuffixGenerator(t, startSequence)
 	if g == nil {
 		return nil, errors.New("Minter sequence value too high")
 	}
-	if g.totalBits > 64 {
-		return nil, errors.New("Template range is too big!  Try a shorter template mask string.")
-	}
 	minter := &Minter{template: t, generator: g}
 
 	return minter, nil
@@ -39,30 +95,151 @@ func (m *Minter) Sequence() uint64 {
 	return m.generator.Sequence()
 }
 
+// Exhausted returns true if every noid the minter can create has been minted
+// or held
+func (m *Minter) Exhausted() bool {
+	if len(m.returned) > 0 || len(m.rollover) > 0 {
+		return false
+	}
+	if m.template.dated() && m.bucket != "" {
+		state, _ := m.bucketState(m.template.BucketFor(m.now()))
+		return state.Exhausted
+	}
+	return m.exhausted
+}
+
+// Minted returns true if the minter has already handed out the given noid.
+// Held noids, noids from other shards, and noids Mint skipped because they
+// were blocked at the time are never considered minted; changing the
+// blocklist afterward doesn't change the answer.  Noids from templates the
+// minter has rolled over from were all minted unless they were skipped,
+// held, or handed back from a lease.
+func (m *Minter) Minted(id string) (bool, error) {
+	gen, t, err := m.templateFor(id)
+	if err != nil {
+		return false, err
+	}
+	if gen < len(m.generations) {
+		g := m.generations[gen]
+		seq, _ := t.Decode(id)
+		return m.inShard(seq) && !g.holds.contains(seq) && !g.returned.contains(seq) && !g.skipped.contains(seq), nil
+	}
+
+	t, err = m.template.resolve(id)
+	if err != nil {
+		return false, err
+	}
+	seq, err := t.Decode(id)
+	if err != nil {
+		return false, err
+	}
+	if !m.inShard(seq) || m.holds.contains(seq) || m.returned.contains(seq) {
+		return false, nil
+	}
+
+	bucket := ""
+	if m.template.dated() {
+		bucket = t.Prefix
+	}
+	state, ok := m.bucketState(bucket)
+	if !ok || sequenceSet(state.Skipped).contains(seq) {
+		return false, nil
+	}
+	return seq < state.Sequence || state.Exhausted, nil
+}
+
+// MaxBlockedRun is how many blocked noids in a row Mint skips before giving
+// up, so a blocklist which blocks everything can't make it spin through the
+// whole sequence space
+const MaxBlockedRun = 1 << 16
+
+// ErrBlockedRun is returned by MintAndBind when Mint gives up after skipping
+// MaxBlockedRun blocked noids in a row
+var ErrBlockedRun = errors.New("Minter skipped too many blocked noids in a row; the blocklist may block everything")
+
+// Mint returns the next noid, skipping any which are held or blocked.  Once
+// the minter is exhausted, Mint returns an empty string.  It also returns an
+// empty string, without being exhausted, after skipping MaxBlockedRun blocked
+// noids in a row; calling it again picks up where it left off.  Minters with
+// dated templates move to a new bucket, with its own sequence, when the date
+// changes.
+//
+// When the current template runs out, the minter moves on to the next
+// template in its rollover chain, if it has one.  Values returned from leases
+// are minted before the minter's sequence moves on.  A leased client's minter
+// returns an empty string once its lease has expired or been closed.
 func (minter *Minter) Mint() string {
-	result := minter.generator.ToString()
-	minter.generator.NextSequence()
+	if minter.leaseError() != nil {
+		return ""
+	}
+	if id := minter.mintReturned(); id != "" {
+		return id
+	}
+	minter.rollBucket()
 
-	if minter.template.Prefix != "" {
-		result = minter.template.Prefix + "." + result
+	for blocked := 0; minter.skipHeld(); blocked++ {
+		if blocked == MaxBlockedRun {
+			return ""
+		}
+
+		seq := minter.generator.Sequence()
+		id := minter.active().format(minter.generator.ToString())
+		if minter.nextSequence() != nil {
+			minter.exhausted = true
+		}
+
+		if !minter.blocklist.blocks(minter.active(), id) {
+			return id
+		}
+		minter.skipped = minter.skipped.add(SequenceRange{seq, seq})
+	}
+
+	if minter.rollTemplate() {
+		return minter.Mint()
 	}
+	return ""
+}
+
+// Peek returns up to n noids that the next calls to Mint would return, without
+// changing the minter.  Fewer than n are returned if the minter would run out.
+func (m *Minter) Peek(n int) []string {
+	g := *m.generator
+	preview := *m
+	preview.generator = &g
 
-	if minter.template.HasCheckDigit {
-		result = result + string(computeCheckDigit(result))
+	var ids []string
+	for i := 0; i < n; i++ {
+		id := preview.Mint()
+		if id == "" {
+			break
+		}
+		ids = append(ids, id)
 	}
 
-	return result
+	return ids
 }
 
-func computeCheckDigit(s string) rune {
-	tally := 0
-	runes := []rune(ExtendedDigits)
-	for index, ch := range s {
-		idx := strings.IndexRune(ExtendedDigits, ch)
-		if idx == -1 {
-			idx = 0
+// Moves the generator past any held sequence values, marking the minter
+// exhausted if nothing is left.  Returns false if the minter is exhausted.
+func (minter *Minter) skipHeld() bool {
+	if minter.exhausted {
+		return false
+	}
+
+	g := minter.generator
+	max := minter.maxSequence()
+	seq, ok := minter.holds.nextFree(g.sequenceValue, max)
+	for ok && !minter.inShard(seq) {
+		seq, ok = minter.alignToShard(seq, max)
+		if ok {
+			seq, ok = minter.holds.nextFree(seq, max)
 		}
-		tally += idx * (1 + index)
 	}
-	return runes[tally%len(ExtendedDigits)]
+	if !ok || seq > max {
+		minter.exhausted = true
+		return false
+	}
+
+	g.sequenceValue = seq
+	return true
 }
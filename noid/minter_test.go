@@ -1,6 +1,9 @@
 package noid
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+)
 
 func TestMinting(t *testing.T) {
 	str := "foo.seedee"
@@ -62,3 +65,57 @@ func TestCheckDigitWorksWithNoPrefix(t *testing.T) {
 	minter, _ = NewSequencedMinter(str, 1001)
 	assertEqualS("000z99", minter.Mint(), "seedeek one-thousand-first mint", t)
 }
+
+func TestPeekDoesNotMint(t *testing.T) {
+	minter, _ := NewMinter("reedeek")
+	minter.HoldRange(1, 1)
+	ids := minter.Peek(3)
+
+	if len(ids) != 3 {
+		t.Fatalf("Expected 3 noids, got %#v", ids)
+	}
+	assertEqualUint64(0, minter.Sequence(), "sequence after peeking", t)
+	for _, id := range ids {
+		assertEqualS(id, minter.Mint(), "minting after peeking", t)
+	}
+}
+
+func TestPeekStopsAtExhaustion(t *testing.T) {
+	minter, _ := NewSequencedMinter("sdd", 60)
+	minter.HoldRange(62, 62)
+	ids := minter.Peek(10)
+
+	if len(ids) != 3 {
+		t.Fatalf("Expected 3 noids, got %#v", ids)
+	}
+	assertEqualS("77", ids[2], "last peeked noid", t)
+	if minter.Exhausted() {
+		t.Errorf("Peeking shouldn't exhaust the minter")
+	}
+}
+
+func TestMintingWithCustomSeparators(t *testing.T) {
+	minter, _ := NewMinter("lib.ms.seedee")
+	assertEqualS("lib.ms.00000", minter.Mint(), "lib.ms.seedee first mint", t)
+
+	minter, _ = NewMinter("x5seedee")
+	assertEqualS("x500000", minter.Mint(), "x5seedee first mint", t)
+
+	minter, _ = NewMinter("foo-seedeek")
+	assertEqualS("foo-00000f", minter.Mint(), "foo-seedeek first mint", t)
+}
+
+func TestCustomSeparatorsSurviveSerialization(t *testing.T) {
+	var buf bytes.Buffer
+
+	minter, _ := NewMinter("lib/ms-reedeek")
+	minter.Mint()
+	minter.WriteJSON(&buf)
+
+	minter, err := NewMinterFromJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unable to read minter: %s", err)
+	}
+	assertEqualS("lib/ms-reedeek", minter.Template(), "template after a round trip", t)
+	assertEqualUint64(1, minter.Sequence(), "sequence after a round trip", t)
+}
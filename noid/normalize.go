@@ -0,0 +1,152 @@
+package noid
+
+// This file handles cleaning up noids which were typed in by hand
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalizer describes how hand-typed noids are cleaned up before they're
+// validated.  Separators are removed from everything after the prefix, and
+// characters which aren't in the alphabet for their position are replaced by
+// their other case or by their entry in Confusables, if either of those is in
+// the alphabet.
+type Normalizer struct {
+	Separators  string
+	Confusables map[rune]rune
+}
+
+// DefaultNormalizer strips spaces and hyphens, and maps confusable characters
+// the way Crockford's base32 does: "o" is zero, and "i" and "l" are one
+var DefaultNormalizer = &Normalizer{
+	Separators:  " -",
+	Confusables: map[rune]rune{'o': '0', 'i': '1', 'l': '1'},
+}
+
+// Normalize cleans up a hand-typed noid using DefaultNormalizer.  See
+// Normalizer.Normalize.
+func (t *Template) Normalize(input string) (string, bool) {
+	return DefaultNormalizer.Normalize(t, input)
+}
+
+// Normalize returns the cleaned-up form of a hand-typed noid for the given
+// template, and whether that differs from what was typed.  A corrected noid
+// still has to be validated; a check digit is what confirms the correction
+// was right.  If the prefix doesn't match the template, even ignoring case,
+// the input is returned as-is.
+func (n *Normalizer) Normalize(t *Template, input string) (string, bool) {
+	s := strings.TrimSpace(input)
+	if r, err := t.resolve(s); err == nil {
+		t = r
+	}
+
+	head := t.Prefix + t.Separator
+	qualifier := ""
+	if t.ARK != nil {
+		p, err := splitARK(s)
+		if err != nil {
+			return input, false
+		}
+		head = t.ARK.String()
+		s = arkLabel + p.NAAN + "/" + p.Noid
+		qualifier = p.Qualifier
+	}
+
+	if !hasPrefixFold(s, head) {
+		return input, false
+	}
+
+	body := n.fixBody(t, head, n.stripSeparators(t.ungroup(s[len(head):])))
+	result := head + body + qualifier
+	return result, result != input
+}
+
+func (n *Normalizer) stripSeparators(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(n.Separators, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// Fixes each character of the suffix (and check digit, if any) based on the
+// alphabet for its position
+func (n *Normalizer) fixBody(t *Template, head, body string) string {
+	runes := []rune(body)
+	cd := t.checkDigit()
+	end := len(runes)
+	if cd != nil && end > 0 {
+		end--
+	}
+
+	// Mask characters apply from the right, with extra characters on the left
+	// using the first mask character, just as in generation
+	mask := []rune(t.Mask)
+	for i := 0; i < end && len(mask) > 0; i++ {
+		maskIndex := i - (end - len(mask))
+		if maskIndex < 0 {
+			maskIndex = 0
+		}
+
+		mc := lookupMaskCharacter(mask[maskIndex])
+		if mc == nil {
+			continue
+		}
+		runes[i] = n.fixCharacter(runes[i], func(r rune) bool { return mc.indexOf(r) != -1 })
+	}
+
+	// The check digit is fixed to whichever candidate actually verifies
+	if cd != nil && end < len(runes) {
+		checkInput := head + string(runes[:end])
+		if t.ARK != nil {
+			checkInput = strings.TrimPrefix(checkInput, arkLabel)
+		}
+		expected := cd.Compute(checkInput)
+		runes[end] = n.fixCharacter(runes[end], func(r rune) bool { return r == expected })
+	}
+
+	return string(runes)
+}
+
+// Returns the first of char, its other case, or its confusable replacement
+// (in either case) which the valid function accepts, or char if none are
+func (n *Normalizer) fixCharacter(char rune, valid func(rune) bool) rune {
+	candidates := []rune{char, unicode.ToLower(char), unicode.ToUpper(char)}
+	if c, ok := n.Confusables[unicode.ToLower(char)]; ok {
+		candidates = append(candidates, c, unicode.ToUpper(c))
+	}
+
+	for _, c := range candidates {
+		if valid(c) {
+			return c
+		}
+	}
+	return char
+}
+
+// ValidateInput normalizes a hand-typed noid, then validates it, returning the
+// canonical noid and whether the input needed correcting
+func (t *Template) ValidateInput(input string) (string, bool, error) {
+	normalized, corrected := t.Normalize(input)
+	id, err := t.Canonical(normalized)
+	if err != nil {
+		return "", corrected, err
+	}
+
+	return id, corrected, nil
+}
+
+// ValidateInput normalizes and validates a hand-typed noid against the
+// minter's template, falling back to the templates it has rolled over from.
+// See Template.ValidateInput.
+func (m *Minter) ValidateInput(input string) (string, bool, error) {
+	id, corrected, err := m.template.ValidateInput(input)
+	for i := len(m.generations) - 1; err != nil && i >= 0; i-- {
+		if gid, gcorrected, gerr := m.generations[i].template.ValidateInput(input); gerr == nil {
+			return gid, gcorrected, nil
+		}
+	}
+	return id, corrected, err
+}
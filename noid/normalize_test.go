@@ -0,0 +1,93 @@
+package noid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTypedNoids(t *testing.T) {
+	template, _ := NewTemplate("foo.reedeek")
+	minter, _ := NewTemplateMinter(template, 0)
+
+	// Find a noid with both a zero and a one so we can mistype them
+	id := minter.Mint()
+	for !strings.Contains(id[4:], "0") || !strings.Contains(id[4:], "1") {
+		id = minter.Mint()
+	}
+	suffix := id[4:]
+
+	var typed = []string{
+		id,
+		strings.ToUpper(id),
+		"foo." + suffix[:2] + "-" + suffix[2:4] + " " + suffix[4:],
+		" " + id + " ",
+		"Foo." + strings.Replace(suffix, "1", "I", -1),
+		"foo." + strings.Replace(suffix, "1", "l", -1),
+		"foo." + strings.Replace(suffix, "0", "O", -1),
+	}
+
+	for i, input := range typed {
+		canonical, corrected, err := template.ValidateInput(input)
+		if err != nil {
+			t.Errorf("Expected %#v to normalize to a valid noid: %s", input, err)
+			continue
+		}
+		assertEqualS(id, canonical, "normalizing "+input, t)
+		if corrected != (i > 0) {
+			t.Errorf("Expected correction of %#v to be %v", input, i > 0)
+		}
+	}
+}
+
+func TestNormalizeCannotFixEverything(t *testing.T) {
+	template, _ := NewTemplate("foo.reedeek")
+
+	minter, _ := NewTemplateMinter(template, 0)
+	id := minter.Mint()
+	wrongCheck := id[:len(id)-1] + "0"
+	if wrongCheck == id {
+		wrongCheck = id[:len(id)-1] + "1"
+	}
+
+	for _, input := range []string{"bar" + id[3:], wrongCheck, id[:len(id)-1]} {
+		if _, _, err := template.ValidateInput(input); err == nil {
+			t.Errorf("Expected %#v to be invalid even after normalizing", input)
+		}
+	}
+}
+
+func TestNormalizeRespectsAlphabetCase(t *testing.T) {
+	registerTestAlphabet('C', crockfordUpper, t)
+	template, _ := NewTemplate("sCCC")
+
+	normalized, corrected := template.Normalize("a-b-o")
+	assertEqualS("AB0", normalized, "normalizing for an uppercase alphabet", t)
+	if !corrected {
+		t.Errorf("Expected normalization to report a correction")
+	}
+}
+
+func TestNormalizeARKs(t *testing.T) {
+	template, _ := NewARKTemplate("12345", "x5", "reedeek")
+	template.CheckDigit = NCDA
+	minter, _ := NewTemplateMinter(template, 0)
+	id := minter.Mint()
+
+	typed := "ARK:/12345/X5" + strings.ToUpper(id[13:16]) + "-" + id[16:] + "/page2"
+	canonical, corrected, err := template.ValidateInput(typed)
+	if err != nil {
+		t.Fatalf("Unable to validate typed ARK: %s", err)
+	}
+	assertEqualS(id, canonical, "typed ARK", t)
+	if !corrected {
+		t.Errorf("Expected typed ARK to need correcting")
+	}
+}
+
+func TestCustomNormalizer(t *testing.T) {
+	template, _ := NewTemplate("seeee")
+	n := &Normalizer{Separators: "_", Confusables: map[rune]rune{'e': '3'}}
+
+	normalized, _ := n.Normalize(template, "A_b_E_d")
+	assertEqualS("ab3d", normalized, "custom normalization", t)
+}
@@ -0,0 +1,222 @@
+package noid
+
+// This file handles presenting minted noids as identifiers in other schemes:
+// DOI suffixes, Handles, and URN:NBNs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Profile turns a noid into an identifier in some scheme and back again.
+// Profiles sit on top of a template rather than replacing it, so a single
+// minter can hand out the same noids as DOIs, Handles, and URN:NBNs.  ARKs
+// are built into templates instead; see ARK.
+type Profile interface {
+	// Name identifies the scheme, e.g., "doi"
+	Name() string
+
+	// Format returns the scheme's identifier for the given noid
+	Format(id string) string
+
+	// Parse returns the noid in the given identifier, or an error if the
+	// identifier doesn't belong to this profile
+	Parse(s string) (string, error)
+}
+
+// DOI is a profile for DOIs using minted noids as their suffixes, e.g.,
+// "10.1234/q67j4g".  DOIs are case-insensitive, so parsing ignores the case
+// of the "doi:" label or resolver URL and prefix, but the suffix is left
+// alone for the template to check.
+type DOI struct {
+	Prefix string
+}
+
+// Handle is a profile for Handles using minted noids as their local names,
+// e.g., "20.500.12345/q67j4g"
+type Handle struct {
+	Prefix string
+}
+
+// URNNBNProfile is a profile for URN:NBNs using minted noids as the NBN
+// string: "urn:nbn:" + Namespace + "-" + noid + check digit, e.g.,
+// "urn:nbn:de:gbv:089-q67j4g3".  The check digit is the URNNBN algorithm over
+// the whole URN.  URNs are case-insensitive, so parsing ignores the case of
+// everything up to the noid.
+type URNNBNProfile struct {
+	Namespace string
+}
+
+// NewDOI returns a DOI profile for the given prefix, e.g., "10.1234"
+func NewDOI(prefix string) (*DOI, error) {
+	parts := strings.Split(prefix, ".")
+	if len(parts) < 2 || parts[0] != "10" || !allDigits(parts[1:]...) {
+		return nil, fmt.Errorf("DOI prefix %#v must be \"10.\" followed by a registrant code, e.g., \"10.1234\"", prefix)
+	}
+	return &DOI{Prefix: prefix}, nil
+}
+
+// NewHandle returns a Handle profile for the given prefix, e.g.,
+// "20.500.12345"
+func NewHandle(prefix string) (*Handle, error) {
+	if !allDigits(strings.Split(prefix, ".")...) {
+		return nil, fmt.Errorf("Handle prefix %#v must be dot-separated numbers, e.g., \"20.500.12345\"", prefix)
+	}
+	return &Handle{Prefix: prefix}, nil
+}
+
+// NewURNNBN returns a URN:NBN profile for the given namespace: a two-letter
+// country code followed by colon-separated sub-namespaces, e.g.,
+// "de:gbv:089"
+func NewURNNBN(namespace string) (*URNNBNProfile, error) {
+	parts := strings.Split(strings.ToLower(namespace), ":")
+	if len(parts[0]) != 2 || !isAlphanumeric(parts[0]) {
+		return nil, fmt.Errorf("URN:NBN namespace %#v must start with a two-letter country code", namespace)
+	}
+	for _, part := range parts[1:] {
+		if part == "" || !isAlphanumeric(part) {
+			return nil, fmt.Errorf("URN:NBN namespace %#v must be colon-separated letters and digits", namespace)
+		}
+	}
+	return &URNNBNProfile{Namespace: strings.ToLower(namespace)}, nil
+}
+
+func allDigits(parts ...string) bool {
+	for _, part := range parts {
+		if part == "" {
+			return false
+		}
+		for _, char := range part {
+			if char < '0' || char > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for _, char := range s {
+		if !(char >= 'a' && char <= 'z' || char >= '0' && char <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Strips the first of the given labels s starts with, ignoring case
+func trimLabelFold(s string, labels ...string) string {
+	for _, label := range labels {
+		if hasPrefixFold(s, label) {
+			return s[len(label):]
+		}
+	}
+	return s
+}
+
+// Returns whatever follows prefix + "/" in s, ignoring case
+func localName(s, prefix, scheme string) (string, error) {
+	if !hasPrefixFold(s, prefix+"/") {
+		return "", fmt.Errorf("%#v isn't a %s under %#v", s, scheme, prefix)
+	}
+	name := s[len(prefix)+1:]
+	if name == "" {
+		return "", fmt.Errorf("%#v has no local name", s)
+	}
+	return name, nil
+}
+
+func (d *DOI) Name() string {
+	return "doi"
+}
+
+func (d *DOI) Format(id string) string {
+	return d.Prefix + "/" + id
+}
+
+// Parse accepts a bare DOI, a "doi:" DOI, or a doi.org URL
+func (d *DOI) Parse(s string) (string, error) {
+	s = trimLabelFold(strings.TrimSpace(s), "https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:")
+	return localName(s, d.Prefix, "DOI")
+}
+
+func (h *Handle) Name() string {
+	return "handle"
+}
+
+func (h *Handle) Format(id string) string {
+	return h.Prefix + "/" + id
+}
+
+// Parse accepts a bare Handle, an "hdl:" Handle, or an hdl.handle.net URL
+func (h *Handle) Parse(s string) (string, error) {
+	s = trimLabelFold(strings.TrimSpace(s), "https://hdl.handle.net/", "http://hdl.handle.net/", "hdl:")
+	return localName(s, h.Prefix, "Handle")
+}
+
+func (u *URNNBNProfile) Name() string {
+	return "urn:nbn"
+}
+
+// Returns everything before the NBN string, e.g., "urn:nbn:de:gbv:089-"
+func (u *URNNBNProfile) head() string {
+	return "urn:nbn:" + u.Namespace + "-"
+}
+
+func (u *URNNBNProfile) Format(id string) string {
+	urn := u.head() + id
+	return urn + string(URNNBN.Compute(urn))
+}
+
+// Parse verifies the URN's check digit and strips it along with the namespace
+func (u *URNNBNProfile) Parse(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	head := u.head()
+	if !hasPrefixFold(s, head) {
+		return "", fmt.Errorf("%#v isn't a URN:NBN in namespace %#v", s, u.Namespace)
+	}
+
+	runes := []rune(s[len(head):])
+	if len(runes) < 2 {
+		return "", fmt.Errorf("%#v is too short to have a check digit", s)
+	}
+	last := len(runes) - 1
+	id := string(runes[:last])
+	if URNNBN.Compute(head+id) != runes[last] {
+		return "", fmt.Errorf("%#v has an incorrect check digit", s)
+	}
+
+	return id, nil
+}
+
+// FormatAs validates a noid and returns its canonical form as an identifier
+// in the profile's scheme
+func (t *Template) FormatAs(p Profile, id string) (string, error) {
+	id, err := t.Canonical(id)
+	if err != nil {
+		return "", err
+	}
+	return p.Format(id), nil
+}
+
+// ParseAs pulls the noid out of an identifier in the profile's scheme,
+// returning the noid's canonical form if it's valid for the template
+func (t *Template) ParseAs(p Profile, s string) (string, error) {
+	id, err := p.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return t.Canonical(id)
+}
+
+// FormatAs formats a noid using the minter's template.  See
+// Template.FormatAs.
+func (m *Minter) FormatAs(p Profile, id string) (string, error) {
+	return m.template.FormatAs(p, id)
+}
+
+// ParseAs parses an identifier using the minter's template.  See
+// Template.ParseAs.
+func (m *Minter) ParseAs(p Profile, s string) (string, error) {
+	return m.template.ParseAs(p, s)
+}
@@ -0,0 +1,124 @@
+package noid
+
+import (
+	"testing"
+)
+
+func TestURNNBNCheckDigit(t *testing.T) {
+	// The German National Library's own example
+	assertEqualS("5", string(URNNBN.Compute("urn:nbn:de:gbv:089-332175294")), "URN:NBN check digit", t)
+	assertEqualS("5", string(URNNBN.Compute("URN:NBN:DE:GBV:089-332175294")), "uppercase URN:NBN check digit", t)
+
+	template, _ := NewTemplate("urn:nbn:de:gbv:089-seeeeeeeeek")
+	template.CheckDigit = URNNBN
+	id := "urn:nbn:de:gbv:089-3321752945"
+	seq, err := template.Decode(id)
+	if err != nil {
+		t.Fatalf("Unable to decode %#v: %s", id, err)
+	}
+	m, _ := NewTemplateMinter(template, seq)
+	assertEqualS(id, m.Mint(), "minted URN:NBN", t)
+}
+
+func TestProfiles(t *testing.T) {
+	doi, _ := NewDOI("10.1234")
+	handle, _ := NewHandle("20.500.12345")
+	urn, _ := NewURNNBN("DE:gbv:089")
+	m, _ := NewMinter("reedeek")
+
+	for i := 0; i < 20; i++ {
+		id := m.Mint()
+		for _, tc := range []struct {
+			p        Profile
+			expected string
+		}{
+			{doi, "10.1234/" + id},
+			{handle, "20.500.12345/" + id},
+			{urn, "urn:nbn:de:gbv:089-" + id + string(URNNBN.Compute("urn:nbn:de:gbv:089-"+id))},
+		} {
+			s, err := m.FormatAs(tc.p, id)
+			if err != nil {
+				t.Fatalf("Unable to format %#v as %s: %s", id, tc.p.Name(), err)
+			}
+			assertEqualS(tc.expected, s, tc.p.Name(), t)
+
+			parsed, err := m.ParseAs(tc.p, s)
+			if err != nil {
+				t.Fatalf("Unable to parse %#v: %s", s, err)
+			}
+			assertEqualS(id, parsed, "parsed "+tc.p.Name(), t)
+		}
+	}
+}
+
+func TestProfileParsing(t *testing.T) {
+	doi, _ := NewDOI("10.1234")
+	handle, _ := NewHandle("20.500.12345")
+	urn, _ := NewURNNBN("de:gbv:089")
+
+	for _, tc := range []struct {
+		p        Profile
+		s        string
+		expected string
+	}{
+		{doi, "doi:10.1234/abc", "abc"},
+		{doi, "https://doi.org/10.1234/abc", "abc"},
+		{doi, "DOI:10.1234/abc", "abc"},
+		{handle, "hdl:20.500.12345/abc", "abc"},
+		{handle, "https://hdl.handle.net/20.500.12345/abc", "abc"},
+		{urn, "URN:NBN:DE:GBV:089-3321752945", "332175294"},
+	} {
+		id, err := tc.p.Parse(tc.s)
+		if err != nil {
+			t.Errorf("Unable to parse %#v: %s", tc.s, err)
+			continue
+		}
+		assertEqualS(tc.expected, id, "parsed "+tc.s, t)
+	}
+
+	for _, tc := range []struct {
+		p Profile
+		s string
+	}{
+		{doi, "10.4321/abc"},
+		{doi, "10.1234/"},
+		{doi, "10.12345/abc"},
+		{handle, "20.500.1234/abc"},
+		{urn, "urn:nbn:de:gbv:089-3321752946"},
+		{urn, "urn:nbn:de:gbv:088-3321752945"},
+		{urn, "urn:nbn:de:gbv:089-3"},
+	} {
+		if _, err := tc.p.Parse(tc.s); err == nil {
+			t.Errorf("Expected %#v not to parse as a %s", tc.s, tc.p.Name())
+		}
+	}
+}
+
+func TestProfileValidatesNoids(t *testing.T) {
+	doi, _ := NewDOI("10.1234")
+	template, _ := NewTemplate("reedeek")
+	if _, err := template.ParseAs(doi, "10.1234/zzzzzzz"); err == nil {
+		t.Errorf("Expected an invalid noid in a valid DOI to be rejected")
+	}
+	if _, err := template.FormatAs(doi, "zzzzzzz"); err == nil {
+		t.Errorf("Expected formatting an invalid noid to fail")
+	}
+}
+
+func TestBadProfiles(t *testing.T) {
+	for _, prefix := range []string{"", "10", "11.1234", "10.12a4", "10.1234/"} {
+		if _, err := NewDOI(prefix); err == nil {
+			t.Errorf("Expected DOI prefix %#v to be invalid", prefix)
+		}
+	}
+	for _, prefix := range []string{"", "20..1", "20.500/1", "abc"} {
+		if _, err := NewHandle(prefix); err == nil {
+			t.Errorf("Expected Handle prefix %#v to be invalid", prefix)
+		}
+	}
+	for _, ns := range []string{"", "d", "deu:x", "de::x", "de:g-v"} {
+		if _, err := NewURNNBN(ns); err == nil {
+			t.Errorf("Expected URN:NBN namespace %#v to be invalid", ns)
+		}
+	}
+}
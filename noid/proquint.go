@@ -0,0 +1,37 @@
+package noid
+
+// This file handles pronounceable noids built from proquints: five-letter
+// syllable groups like "lusab" which each hold sixteen bits
+
+import (
+	"strings"
+)
+
+// The alphabets for the "c" (consonant) and "v" (vowel) mask characters.  A
+// "cvcvc" mask is exactly one proquint, since the rightmost mask character
+// holds the lowest bits, just as the last letter of a proquint does.
+const (
+	ProquintConsonants = "bdfghjklmnprstvz"
+	ProquintVowels     = "aiou"
+)
+
+// ProquintMask returns a mask of the given number of proquints, e.g.,
+// ProquintMask(2) returns "cvcvccvcvc".  Set a template's GroupSize to 5 to
+// separate the proquints with hyphens, as in "lusab-babad".
+func ProquintMask(words int) string {
+	return strings.Repeat("cvcvc", words)
+}
+
+// NewProquintTemplate returns a template for noids made up of the given number
+// of proquints, separated by hyphens, e.g.:
+//
+//	NewProquintTemplate("", Random, 2)   // "lusab-babad"
+func NewProquintTemplate(prefix string, ordering Ordering, words int) (*Template, error) {
+	t, err := NewTemplate(prefix + string(ordering.char()) + ProquintMask(words))
+	if err != nil {
+		return nil, err
+	}
+
+	t.GroupSize = 5
+	return t, nil
+}
@@ -0,0 +1,70 @@
+package noid
+
+import (
+	"testing"
+)
+
+func TestProquints(t *testing.T) {
+	template, err := NewProquintTemplate("", SequentialLimited, 2)
+	if err != nil {
+		t.Fatalf("Unable to create proquint template: %s", err)
+	}
+	assertEqualS("scvcvccvcvc", template.String(), "proquint template", t)
+
+	// Examples from the proquint spec, which encodes IPv4 addresses
+	for _, tc := range []struct {
+		seq      uint64
+		expected string
+	}{
+		{0x7f000001, "lusab-babad"},
+		{0x3f54dcc1, "gutih-tugad"},
+		{0, "babab-babab"},
+		{0xffffffff, "zuzuz-zuzuz"},
+	} {
+		m, err := NewTemplateMinter(template, tc.seq)
+		if err != nil {
+			t.Fatalf("Unable to create minter at %d: %s", tc.seq, err)
+		}
+		id := m.Mint()
+		assertEqualS(tc.expected, id, "proquint", t)
+
+		seq, err := template.Decode(id)
+		if err != nil {
+			t.Errorf("Unable to decode %#v: %s", id, err)
+		}
+		assertEqualUint64(tc.seq, seq, "decoded proquint", t)
+	}
+
+	if _, err := NewTemplateMinter(template, 0x100000000); err == nil {
+		t.Errorf("Expected two proquints to hold only 32 bits")
+	}
+}
+
+func TestRandomProquints(t *testing.T) {
+	template, _ := NewProquintTemplate("ref.", Random, 1)
+	m, _ := NewTemplateMinter(template, 0)
+
+	seen := make(map[string]bool)
+	for i := uint64(0); i < 1000; i++ {
+		id := m.Mint()
+		if seen[id] {
+			t.Fatalf("Duplicate proquint %#v", id)
+		}
+		seen[id] = true
+
+		seq, err := template.Decode(id)
+		if err != nil {
+			t.Fatalf("Unable to decode %#v: %s", id, err)
+		}
+		assertEqualUint64(i, seq, "decoded random proquint", t)
+	}
+
+	// Hand-typed proquints can use any case and leave out the hyphen
+	id := m.Mint()
+	typed := "REF." + id[4:6] + " " + id[6:]
+	canonical, _, err := template.ValidateInput(typed)
+	if err != nil {
+		t.Fatalf("Unable to validate %#v: %s", typed, err)
+	}
+	assertEqualS(id, canonical, "normalized proquint", t)
+}
@@ -0,0 +1,98 @@
+package noid
+
+// This file handles rebuilding a minter from the noids it has already minted
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RecoveryError describes a line Recover couldn't use
+type RecoveryError struct {
+	Line int
+	Text string
+	Err  error
+}
+
+func (e RecoveryError) Error() string {
+	return fmt.Sprintf("line %d (%#v): %s", e.Line, e.Text, e.Err)
+}
+
+// Recover reads noids from r, one per line, and returns a minter for the
+// given template whose sequence is just past the highest sequence value
+// found.  Blank lines are skipped, and lines which aren't valid noids for the
+// template are returned as RecoveryErrors rather than stopping the recovery.
+//
+// For random templates, sequence values below the highest one which weren't
+// in the input are simply lost, since we can't know they were never handed
+// out.
+func Recover(template string, r io.Reader) (*Minter, []RecoveryError, error) {
+	t, err := NewTemplate(template)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return RecoverTemplate(t, r)
+}
+
+// RecoverTemplate is Recover for an already-parsed template.  Dated templates
+// get a sequence for each date bucket found in the input.
+func RecoverTemplate(t *Template, r io.Reader) (*Minter, []RecoveryError, error) {
+	var err error
+
+	var badLines []RecoveryError
+	highest := make(map[string]uint64)
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		seq, err := t.Decode(line)
+		if err != nil {
+			badLines = append(badLines, RecoveryError{Line: lineNum, Text: line, Err: err})
+			continue
+		}
+
+		bucket, _ := t.Bucket(line)
+		if prev, found := highest[bucket]; !found || seq > prev {
+			highest[bucket] = seq
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, badLines, err
+	}
+
+	m, err := NewTemplateMinter(t, 0)
+	if err != nil {
+		return nil, badLines, err
+	}
+
+	for bucket, seq := range highest {
+		g := NewSuffixGenerator(m.template, seq)
+		state := BucketState{Sequence: seq}
+		if g.NextSequence() != nil {
+			state.Exhausted = true
+		} else {
+			state.Sequence = g.Sequence()
+		}
+
+		if bucket == "" {
+			m.generator = g
+			m.exhausted = state.Exhausted
+			continue
+		}
+		if m.buckets == nil {
+			m.buckets = make(map[string]BucketState)
+		}
+		m.buckets[bucket] = state
+	}
+
+	return m, badLines, nil
+}
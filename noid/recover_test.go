@@ -0,0 +1,46 @@
+package noid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	minter, _ := NewMinter("foo.reedeek")
+	var ids []string
+	for i := 0; i < 50; i++ {
+		ids = append(ids, minter.Mint())
+	}
+
+	// Shuffle things up a bit and throw in some garbage
+	input := strings.Join([]string{ids[40], "", ids[3], "foo.bogus", ids[49], "  " + ids[12] + "  ", "bar.q67j4g"}, "\n")
+	recovered, badLines, err := Recover("foo.reedeek", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	assertEqualUint64(50, recovered.Sequence(), "recovered sequence", t)
+	assertEqualS(minter.Mint(), recovered.Mint(), "next noid after recovery", t)
+
+	if len(badLines) != 2 {
+		t.Fatalf("Expected 2 bad lines, got %#v", badLines)
+	}
+	if badLines[0].Line != 4 || badLines[1].Line != 7 {
+		t.Errorf("Expected bad lines 4 and 7, got %d and %d", badLines[0].Line, badLines[1].Line)
+	}
+}
+
+func TestRecoverEmptyInput(t *testing.T) {
+	recovered, badLines, err := Recover("reedeek", strings.NewReader("\n\n"))
+	if err != nil || len(badLines) != 0 {
+		t.Fatalf("Unexpected errors: %s, %#v", err, badLines)
+	}
+	assertEqualUint64(0, recovered.Sequence(), "sequence with no noids", t)
+}
+
+func TestRecoverLastNoid(t *testing.T) {
+	recovered, _, _ := Recover("sdd", strings.NewReader("77\n"))
+	if !recovered.Exhausted() {
+		t.Errorf("Expected minter to be exhausted after recovering the last noid")
+	}
+}
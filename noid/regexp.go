@@ -0,0 +1,134 @@
+package noid
+
+// This file handles describing a template's noids as a regular expression
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RegexpPattern returns an anchored regular expression matching the noids the
+// template mints.  The pattern sticks to syntax which RE2, PCRE, PostgreSQL,
+// and ECMAScript all agree on: character classes, non-capturing groups, and
+// bounded repetition.
+//
+// The pattern can't check a check digit, only that it's one of the characters
+// the algorithm produces, so it accepts some noids Validate rejects.  It also
+// only matches the normalized form of ARKs.  Group separators are allowed
+// before any character, just as Validate ignores them wherever they are.  For
+// "z" templates, the number of extra characters is capped at what 64 bits can
+// hold, but the largest values at that length aren't ruled out.
+func (t *Template) RegexpPattern() string {
+	return "^" + t.unanchoredPattern() + "$"
+}
+
+func (t *Template) unanchoredPattern() string {
+	sep := ""
+	if t.GroupSize > 0 {
+		sep = "(?:" + regexp.QuoteMeta(t.groupSeparator()) + ")?"
+	}
+
+	var b strings.Builder
+	if t.ARK != nil {
+		b.WriteString(regexp.QuoteMeta(arkLabel + t.ARK.NAAN + "/"))
+		for _, char := range t.ARK.Shoulder {
+			b.WriteString(sep + regexp.QuoteMeta(string(char)))
+		}
+	} else {
+		b.WriteString(t.prefixPattern() + regexp.QuoteMeta(t.Separator))
+	}
+
+	mask := []rune(t.Mask)
+	if t.Ordering == SequentialUnlimited && len(mask) > 0 {
+		b.WriteString(t.growthPattern(sep))
+	}
+	for _, char := range mask {
+		b.WriteString(sep + characterClass(lookupMaskCharacter(char).alphabet))
+	}
+
+	if cd := t.checkDigit(); cd != nil {
+		class := "."
+		if cc, ok := cd.(CheckCharacters); ok {
+			class = characterClass([]rune(cc.Characters()))
+		}
+		b.WriteString(sep + class)
+	}
+
+	return b.String()
+}
+
+// Regexp returns RegexpPattern compiled for use in Go
+func (t *Template) Regexp() *regexp.Regexp {
+	return regexp.MustCompile(t.RegexpPattern())
+}
+
+// Returns the pattern for the extra characters an unlimited template adds
+// once its mask is full: they use the first mask character's alphabet, and
+// the leftmost can't be that alphabet's zero
+func (t *Template) growthPattern(sep string) string {
+	mc := lookupMaskCharacter([]rune(t.Mask)[0])
+	extra := t.growthLength()
+	if extra < 1 {
+		return ""
+	}
+
+	p := "(?:" + sep + characterClass(mc.alphabet[1:])
+	if extra > 1 {
+		p += "(?:" + sep + characterClass(mc.alphabet) + "){0," + strconv.Itoa(extra-1) + "}"
+	}
+	return p + ")?"
+}
+
+// Returns the most characters an unlimited template can add to its mask
+// before its sequence runs past 64 bits
+func (t *Template) growthLength() int {
+	mc := lookupMaskCharacter([]rune(t.Mask)[0])
+	bits, _ := t.maskBits()
+	return (64 - bits + int(mc.bits) - 1) / int(mc.bits)
+}
+
+// Builds a bracketed character class for the given characters, collapsing
+// runs of three or more consecutive characters into ranges
+func characterClass(chars []rune) string {
+	sorted := append([]rune(nil), chars...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	if len(sorted) == 1 {
+		return regexp.QuoteMeta(string(sorted[0]))
+	}
+
+	var b strings.Builder
+	b.WriteString("[")
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+
+		b.WriteString(escapeClassCharacter(sorted[i]))
+		if j-i >= 2 {
+			b.WriteString("-" + escapeClassCharacter(sorted[j]))
+		} else if j > i {
+			b.WriteString(escapeClassCharacter(sorted[j]))
+		}
+		i = j + 1
+	}
+	b.WriteString("]")
+
+	return b.String()
+}
+
+func escapeClassCharacter(char rune) string {
+	if strings.ContainsRune(`\]-[^`, char) {
+		return `\` + string(char)
+	}
+	return string(char)
+}
+
+// RegexpPattern returns the pattern for the minter's template.  See
+// Template.RegexpPattern.
+func (m *Minter) RegexpPattern() string {
+	return m.template.RegexpPattern()
+}
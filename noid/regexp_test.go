@@ -0,0 +1,99 @@
+package noid
+
+import (
+	"testing"
+)
+
+func TestCharacterClass(t *testing.T) {
+	assertEqualS("[0-7]", characterClass([]rune("01234567")), "digit class", t)
+	assertEqualS("[0-9a-df-hjkmnp-z]", characterClass([]rune(ExtendedDigits)), "extended digit class", t)
+	assertEqualS("[0-9X]", characterClass([]rune("0123456789X")), "ISO 7064 class", t)
+	assertEqualS(`[\-ab]`, characterClass([]rune("ba-")), "escaped class", t)
+	assertEqualS(`x`, characterClass([]rune("x")), "single character", t)
+}
+
+func TestRegexpPattern(t *testing.T) {
+	for _, tc := range [][2]string{
+		{"reedd", "^[0-9a-df-hjkmnp-z][0-9a-df-hjkmnp-z][0-7][0-7]$"},
+		{"foo.sddk", `^foo\.[0-7][0-7][0-9a-df-hjkmnp-z]$`},
+		{"ark:/12345/x5sdk", `^ark:/12345/x5[0-7][0-9a-df-hjkmnp-z]$`},
+		{"zdd", "^(?:[1-7](?:[0-7]){0,19})?[0-7][0-7]$"},
+	} {
+		template, _ := NewTemplate(tc[0])
+		assertEqualS(tc[1], template.RegexpPattern(), "pattern for "+tc[0], t)
+	}
+
+	template, _ := NewTemplate("sddk")
+	template.CheckDigit = ISO7064Mod112
+	template.GroupSize = 2
+	assertEqualS("^(?:-)?[0-7](?:-)?[0-7](?:-)?[0-9X]$", template.RegexpPattern(), "grouped ISO 7064 pattern", t)
+}
+
+// Returns the given noid with one change at every position from start on:
+// each character swapped for others, characters dropped, and characters added
+func mutations(id string, start int) []string {
+	const pool = "0189abzxyAZ_~"
+	runes := []rune(id)
+	var results []string
+	for i := start; i <= len(runes); i++ {
+		for _, char := range pool {
+			inserted := append(append(append([]rune(nil), runes[:i]...), char), runes[i:]...)
+			results = append(results, string(inserted))
+			if i < len(runes) {
+				replaced := append([]rune(nil), runes...)
+				replaced[i] = char
+				results = append(results, string(replaced))
+			}
+		}
+		if i < len(runes) {
+			results = append(results, string(runes[:i])+string(runes[i+1:]))
+		}
+	}
+	return results
+}
+
+func TestRegexpMatchesValidation(t *testing.T) {
+	for _, str := range []string{"reedd", "foo.sdde", "zdd", "zed", "ark:/12345/x5reed", "lib/ms-seeeee"} {
+		template, _ := NewTemplate(str)
+		re := template.Regexp()
+		m, _ := NewTemplateMinter(template, 0)
+
+		// The head is left alone, since the pattern doesn't cover ARK
+		// normalization
+		for i := 0; i < 40 && !m.Exhausted(); i++ {
+			id := m.Mint()
+			if !re.MatchString(id) {
+				t.Errorf("Expected %s to match minted noid %#v", re, id)
+			}
+
+			for _, s := range mutations(id, len(template.literalHead())) {
+				valid := template.Validate(s) == nil
+				if re.MatchString(s) != valid {
+					t.Errorf("%s: regexp match for %#v is %v, but validity is %v", str, s, !valid, valid)
+				}
+			}
+		}
+	}
+}
+
+func TestRegexpAcceptsEverythingValid(t *testing.T) {
+	for _, str := range []string{"reedk", "ark:/12345/x5reedk", "zedk"} {
+		template, _ := NewTemplate(str)
+		template.GroupSize = 2
+		re := template.Regexp()
+		m, _ := NewTemplateMinter(template, 0)
+
+		for i := 0; i < 40; i++ {
+			id := m.Mint()
+			if !re.MatchString(id) {
+				t.Errorf("Expected %s to match minted noid %#v", re, id)
+			}
+
+			for _, s := range mutations(id, len(template.literalHead())) {
+				if template.Validate(s) == nil && !re.MatchString(s) {
+					t.Errorf("Expected %s to match valid noid %#v", re, s)
+				}
+			}
+		}
+	}
+}
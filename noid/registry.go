@@ -0,0 +1,125 @@
+package noid
+
+// This file handles figuring out which of several templates a noid came from
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// A Registry holds named templates so that noids from many minters can be
+// traced back to the template which created them
+type Registry struct {
+	mu        sync.RWMutex
+	names     []string
+	templates map[string]*Template
+}
+
+// A Match is a registered template a noid is valid for
+type Match struct {
+	Name      string
+	Template  *Template
+	Canonical string
+	Sequence  uint64
+}
+
+// DefaultRegistry is where an ID without a template looks for one when it's
+// unmarshaled or scanned from a database
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{templates: make(map[string]*Template)}
+}
+
+// Register adds a template under the given name.  The template is copied, so
+// changing it afterward has no effect on the registry.
+func (r *Registry) Register(name string, t *Template) error {
+	if name == "" {
+		return errors.New("Registered templates must have a name")
+	}
+
+	// Building a minter catches anything which couldn't mint noids at all
+	m, err := NewTemplateMinter(t, 0)
+	if err != nil {
+		return fmt.Errorf("Template %#v: %s", name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.templates[name] != nil {
+		return fmt.Errorf("Template %#v is already registered", name)
+	}
+	r.names = append(r.names, name)
+	r.templates[name] = m.template
+
+	return nil
+}
+
+// RegisterMinter adds the given minter's template under the given name
+func (r *Registry) RegisterMinter(name string, m *Minter) error {
+	return r.Register(name, m.template)
+}
+
+// Names returns the names of all registered templates in the order they were
+// registered
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.names...)
+}
+
+// Identify returns every registered template the given noid is valid for:
+// its prefix, length, characters, and check digit all have to fit.  Matches
+// are ranked from most to least specific; see Template.MoreSpecificThan.
+func (r *Registry) Identify(id string) []*Match {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matches []*Match
+	for _, name := range r.names {
+		t := r.templates[name]
+		seq, err := t.Decode(id)
+		if err != nil {
+			continue
+		}
+		canonical, _ := t.Canonical(id)
+		matches = append(matches, &Match{Name: name, Template: t, Canonical: canonical, Sequence: seq})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Template.MoreSpecificThan(matches[j].Template)
+	})
+	return matches
+}
+
+// MoreSpecificThan returns true if t describes a narrower set of noids than
+// other.  A longer literal prefix wins first, since it's the strongest sign of
+// where a noid came from; after that, a template with a check digit beats one
+// without, and finally the template with fewer possible noids wins.
+func (t *Template) MoreSpecificThan(other *Template) bool {
+	if a, b := len(t.literalHead()), len(other.literalHead()); a != b {
+		return a > b
+	}
+	if t.HasCheckDigit != other.HasCheckDigit {
+		return t.HasCheckDigit
+	}
+
+	// Unlimited templates can mint any number of noids, so they're the least
+	// specific of all
+	if (t.Ordering == SequentialUnlimited) != (other.Ordering == SequentialUnlimited) {
+		return other.Ordering == SequentialUnlimited
+	}
+	a, _ := t.maskBits()
+	b, _ := other.maskBits()
+	return a < b
+}
+
+// Returns the fixed text every noid from the template starts with
+func (t *Template) literalHead() string {
+	if t.ARK != nil {
+		return t.ARK.String()
+	}
+	return t.Prefix + t.Separator
+}
@@ -0,0 +1,120 @@
+package noid
+
+import (
+	"testing"
+)
+
+func mustRegister(r *Registry, name, template string, t *testing.T) {
+	tmpl, err := NewTemplate(template)
+	if err != nil {
+		t.Fatalf("Unable to parse %#v: %s", template, err)
+	}
+	if err = r.Register(name, tmpl); err != nil {
+		t.Fatalf("Unable to register %#v: %s", name, err)
+	}
+}
+
+func matchNames(matches []*Match) []string {
+	var names []string
+	for _, m := range matches {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+func assertMatches(expected []string, id string, r *Registry, t *testing.T) {
+	actual := matchNames(r.Identify(id))
+	if len(actual) != len(expected) {
+		t.Errorf("Expected %#v to match %#v, but got %#v", id, expected, actual)
+		return
+	}
+	for i := range expected {
+		assertEqualS(expected[i], actual[i], "match for "+id, t)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	r := NewRegistry()
+	mustRegister(r, "plain", "reeeddd", t)
+	mustRegister(r, "photos", "ph.reeddk", t)
+	mustRegister(r, "digits", "sdddddd", t)
+	mustRegister(r, "ark", "ark:/12345/x5reeddk", t)
+
+	photo, _ := NewMinter("ph.reeddk")
+	id := photo.Mint()
+	assertMatches([]string{"photos"}, id, r, t)
+
+	ark, _ := NewMinter("ark:/12345/x5reeddk")
+	assertMatches([]string{"ark"}, ark.Mint(), r, t)
+
+	// Digits are valid extended digits too, so an all-digit noid fits both;
+	// the narrower template comes first
+	assertMatches([]string{"digits", "plain"}, "012345", r, t)
+	assertMatches([]string{"plain"}, "zz0123", r, t)
+	assertMatches(nil, "ph.zzzzz", r, t)
+	assertMatches(nil, "nope", r, t)
+}
+
+func TestIdentifyReturnsCanonicalAndSequence(t *testing.T) {
+	r := NewRegistry()
+	mustRegister(r, "seq", "foo-seek", t)
+	m, _ := NewMinter("foo-seek")
+	m.Mint()
+	id := m.Mint()
+
+	matches := r.Identify(id)
+	if len(matches) != 1 {
+		t.Fatalf("Expected one match for %#v, got %d", id, len(matches))
+	}
+	assertEqualS(id, matches[0].Canonical, "canonical noid", t)
+	assertEqualUint64(1, matches[0].Sequence, "sequence", t)
+}
+
+func TestSpecificityRanking(t *testing.T) {
+	r := NewRegistry()
+	mustRegister(r, "unlimited", "zd", t)
+	mustRegister(r, "short-prefix", "1zd", t)
+	mustRegister(r, "long-prefix", "12sd", t)
+	mustRegister(r, "limited", "1sdd", t)
+	mustRegister(r, "wide", "1see", t)
+
+	// Longer prefixes first, then limited templates by how many noids they can
+	// mint, then unlimited templates
+	assertMatches([]string{"long-prefix", "limited", "wide", "short-prefix", "unlimited"}, "123", r, t)
+	assertMatches([]string{"limited", "wide", "short-prefix", "unlimited"}, "145", r, t)
+	assertMatches([]string{"wide"}, "1z5", r, t)
+}
+
+func TestCheckDigitsAreMoreSpecific(t *testing.T) {
+	checked, _ := NewTemplate("1seek")
+	unchecked, _ := NewTemplate("1seee")
+	if !checked.MoreSpecificThan(unchecked) {
+		t.Errorf("Expected a template with a check digit to be more specific")
+	}
+	if unchecked.MoreSpecificThan(checked) {
+		t.Errorf("Expected a template without a check digit to be less specific")
+	}
+
+	longer, _ := NewTemplate("12see")
+	if !longer.MoreSpecificThan(checked) {
+		t.Errorf("Expected a longer prefix to beat a check digit")
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	r := NewRegistry()
+	mustRegister(r, "a", "reedd", t)
+
+	tmpl, _ := NewTemplate("reedd")
+	if err := r.Register("a", tmpl); err == nil {
+		t.Errorf("Expected duplicate name to be rejected")
+	}
+	if err := r.Register("", tmpl); err == nil {
+		t.Errorf("Expected empty name to be rejected")
+	}
+
+	huge, _ := NewTemplate("reeeeeeeeeeeeee")
+	if err := r.Register("huge", huge); err == nil {
+		t.Errorf("Expected a template which can't mint to be rejected")
+	}
+}
@@ -0,0 +1,256 @@
+package noid
+
+// This file handles rolling a minter over to a new template once its current
+// one runs out, e.g., from "x.reedeek" to "x.reeedeek"
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A template the minter has used up, along with the sequence values in it
+// which were never minted: those held when the minter rolled over, those
+// handed back unused from leases, and those skipped for being blocked
+type generation struct {
+	template *Template
+	holds    sequenceSet
+	returned sequenceSet
+	skipped  sequenceSet
+}
+
+// SetRollover replaces the chain of templates the minter switches to, in
+// order, each time it's exhausted.  Every template the minter has used or
+// will use is checked against every other, and the chain is rejected if any
+// two could mint the same noid; see Template.Overlaps.  The templates are
+// copied, so changing them afterward has no effect on the minter.
+//
+// Holds are sequence values in a particular template, so they stay with the
+// template they were made for when the minter rolls over, along with any
+// values handed back from its leases.  The blocklist carries over to every
+// link.
+// Dated templates can't be part of a chain, since they already start over
+// with every new date.
+func (m *Minter) SetRollover(templates ...*Template) error {
+	if len(templates) > 0 && m.template.dated() {
+		return errors.New("Templates with date placeholders can't roll over")
+	}
+
+	var chain []*Template
+	for _, t := range templates {
+		link, err := NewTemplateMinter(t, 0)
+		if err != nil {
+			return fmt.Errorf("Rollover template %#v: %s", t.String(), err)
+		}
+		if link.template.dated() {
+			return fmt.Errorf("Rollover template %#v: templates with date placeholders can't roll over", t.String())
+		}
+		if err = m.Shard().validate(link.generator.maxSequence); err != nil {
+			return fmt.Errorf("Rollover template %#v: %s", t.String(), err)
+		}
+		chain = append(chain, link.template)
+	}
+
+	all := append(m.Generations(), chain...)
+	for i, a := range all {
+		for _, b := range all[i+1:] {
+			if a.Overlaps(b) {
+				return fmt.Errorf("Templates %#v and %#v could mint the same noid", a.String(), b.String())
+			}
+		}
+	}
+
+	m.rollover = chain
+	return nil
+}
+
+// Rollover returns copies of the templates the minter hasn't rolled over to
+// yet, in the order it will use them
+func (m *Minter) Rollover() []*Template {
+	return copyTemplates(m.rollover)
+}
+
+// Generations returns copies of every template the minter has minted from,
+// oldest first.  The last is the minter's current template.
+func (m *Minter) Generations() []*Template {
+	var templates []*Template
+	for _, g := range m.generations {
+		templates = append(templates, g.template)
+	}
+	return copyTemplates(append(templates, m.template))
+}
+
+func copyTemplates(templates []*Template) []*Template {
+	var copies []*Template
+	for _, t := range templates {
+		c := *t
+		copies = append(copies, &c)
+	}
+	return copies
+}
+
+// Switches to the next template in the rollover chain, starting its sequence
+// over.  Returns false if the chain is used up.
+func (m *Minter) rollTemplate() bool {
+	if len(m.rollover) == 0 {
+		return false
+	}
+
+	g := generation{template: m.template, holds: m.holds, returned: m.returned, skipped: m.skipped}
+	m.generations = append(append([]generation(nil), m.generations...), g)
+	m.template = m.rollover[0]
+	m.rollover = m.rollover[1:]
+	m.generator = NewSuffixGenerator(m.template, m.Shard().ID)
+	m.exhausted = false
+	m.holds = nil
+	m.returned = nil
+	m.skipped = nil
+
+	return true
+}
+
+// Generation returns which of the minter's templates the given noid belongs
+// to: 0 for the first template, counting up to the current one.  The current
+// template is tried first, and its error is returned if no template fits.
+func (m *Minter) Generation(id string) (int, error) {
+	gen, _, err := m.templateFor(id)
+	return gen, err
+}
+
+// Returns the generation and template which the given noid is valid for
+func (m *Minter) templateFor(id string) (int, *Template, error) {
+	err := m.template.Validate(id)
+	if err == nil {
+		return len(m.generations), m.template, nil
+	}
+
+	for i := len(m.generations) - 1; i >= 0; i-- {
+		if t := m.generations[i].template; t.Validate(id) == nil {
+			return i, t, nil
+		}
+	}
+	return 0, nil, err
+}
+
+// Validate returns an error if the given noid couldn't have been minted from
+// any of the minter's templates
+func (m *Minter) Validate(id string) error {
+	_, _, err := m.templateFor(id)
+	return err
+}
+
+// Decode returns the generation the given noid belongs to and the sequence
+// value which mints it in that generation's template
+func (m *Minter) Decode(id string) (int, uint64, error) {
+	gen, t, err := m.templateFor(id)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	seq, err := t.Decode(id)
+	return gen, seq, err
+}
+
+// Canonical returns the canonical form of a noid from any of the minter's
+// templates
+func (m *Minter) Canonical(id string) (string, error) {
+	_, t, err := m.templateFor(id)
+	if err != nil {
+		return "", err
+	}
+	return t.Canonical(id)
+}
+
+// Overlaps returns true if any string could be a valid noid for both
+// templates.  The check works character by character, comparing the literal
+// prefix characters and the alphabets each position allows, so it's
+// conservative: check digits are only compared by the characters they can
+// produce, and group separators are ignored entirely.  Dated templates are
+// compared using their placeholders as literal text.
+func (t *Template) Overlaps(other *Template) bool {
+	for _, a := range t.shapes() {
+		for _, b := range other.shapes() {
+			if shapesOverlap(a, b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the characters allowed at each position of the template's noids,
+// once for each length the noids can have.  A nil position allows anything.
+func (t *Template) shapes() [][][]rune {
+	var head [][]rune
+	literal := t.Prefix + t.Separator
+	if t.ARK != nil {
+		literal = t.ARK.String()
+	}
+	for _, char := range literal {
+		head = append(head, []rune{char})
+	}
+
+	var mask [][]rune
+	for _, char := range t.Mask {
+		mask = append(mask, lookupMaskCharacter(char).alphabet)
+	}
+
+	var tail [][]rune
+	if cd := t.checkDigit(); cd != nil {
+		var chars []rune
+		if cc, ok := cd.(CheckCharacters); ok {
+			chars = []rune(cc.Characters())
+		}
+		tail = append(tail, chars)
+	}
+
+	// Unlimited templates grow extra characters in front of the mask, using
+	// its first character's alphabet without that alphabet's zero up front
+	extra := 0
+	if t.Ordering == SequentialUnlimited {
+		extra = t.growthLength()
+	}
+	alphabet := mask[0]
+
+	var shapes [][][]rune
+	for n := 0; n <= extra; n++ {
+		var growth [][]rune
+		for i := 0; i < n; i++ {
+			if i == 0 {
+				growth = append(growth, alphabet[1:])
+			} else {
+				growth = append(growth, alphabet)
+			}
+		}
+
+		shape := append(append([][]rune(nil), head...), growth...)
+		shapes = append(shapes, append(append(shape, mask...), tail...))
+	}
+	return shapes
+}
+
+// Returns true if some string fits both shapes
+func shapesOverlap(a, b [][]rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !runesIntersect(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func runesIntersect(a, b []rune) bool {
+	if a == nil || b == nil {
+		return true
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
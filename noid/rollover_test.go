@@ -0,0 +1,206 @@
+package noid
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func mustTemplate(s string, t *testing.T) *Template {
+	template, err := NewTemplate(s)
+	if err != nil {
+		t.Fatalf("Unable to parse template %#v: %s", s, err)
+	}
+	return template
+}
+
+func TestMinterRollsOver(t *testing.T) {
+	m, _ := NewMinter("x.sd")
+	if err := m.SetRollover(mustTemplate("x.sdd", t), mustTemplate("y.sd", t)); err != nil {
+		t.Fatalf("Unable to set rollover: %s", err)
+	}
+
+	seen := make(map[string]bool)
+	for id := m.Mint(); id != ""; id = m.Mint() {
+		if seen[id] {
+			t.Fatalf("Minted %#v twice", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != 8+64+8 {
+		t.Errorf("Expected %d noids across the chain, got %d", 8+64+8, len(seen))
+	}
+	if !m.Exhausted() {
+		t.Errorf("Expected the minter to be exhausted at the end of the chain")
+	}
+	assertEqualS("y.sd", m.Template(), "template at the end of the chain", t)
+
+	for id, gen := range map[string]int{"x.7": 0, "x.00": 1, "y.3": 2} {
+		g, seq, err := m.Decode(id)
+		if err != nil {
+			t.Errorf("Unable to decode %#v: %s", id, err)
+			continue
+		}
+		if g != gen {
+			t.Errorf("Expected %#v to be generation %d, got %d", id, gen, g)
+		}
+		minted, _ := m.Minted(id)
+		if !minted {
+			t.Errorf("Expected %#v (sequence %d) to be minted", id, seq)
+		}
+	}
+	if err := m.Validate("z.3"); err == nil {
+		t.Errorf("Expected an error validating a noid from no generation")
+	}
+	if err := m.Bind("x.5", Bindings{"who": "early"}); err != nil {
+		t.Errorf("Unable to bind a noid from the first generation: %s", err)
+	}
+}
+
+func TestRolloverRejectsCollisions(t *testing.T) {
+	m, _ := NewMinter("x.reedeek")
+
+	for _, link := range []string{"x.seedeek", "x.reedeek", "x.redeeek", "x.zedeek"} {
+		if err := m.SetRollover(mustTemplate(link, t)); err == nil {
+			t.Errorf("Expected %#v to collide with x.reedeek", link)
+		}
+	}
+	if err := m.SetRollover(mustTemplate("x.reeedeek", t), mustTemplate("x.reeeedeek", t)); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := m.SetRollover(mustTemplate("x.reeedeek", t), mustTemplate("x.seeedeek", t)); err == nil {
+		t.Errorf("Expected links in the chain to be checked against each other")
+	}
+
+	// Mask characters with disjoint alphabets can't collide even at the same
+	// length
+	pq, _ := NewMinter("x.rcvcv")
+	if err := pq.SetRollover(mustTemplate("x.rvcvc", t)); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	dated, _ := NewMinter("{YYYY}.reedeek")
+	if err := dated.SetRollover(mustTemplate("x.reeedeek", t)); err == nil {
+		t.Errorf("Expected an error rolling over a dated template")
+	}
+}
+
+func TestTemplateOverlaps(t *testing.T) {
+	var tests = []struct {
+		a, b     string
+		overlaps bool
+	}{
+		{"x.reedeek", "x.reeedeek", false},
+		{"x.reedeek", "y.reedeek", false},
+		{"x.sdd", "x.sde", true},
+		{"x.sdd", "x.zd", true},
+		{"x.zdd", "x.sd", false},
+		{"ark:/12345/x5reedeek", "ark:/12345/x6reedeek", false},
+		{"ark:/12345/x5reedeek", "ark:/12345/x5seedeek", true},
+		{"x5reedee", "x.reedee", false},
+	}
+
+	for _, test := range tests {
+		a, b := mustTemplate(test.a, t), mustTemplate(test.b, t)
+		if a.Overlaps(b) != test.overlaps || b.Overlaps(a) != test.overlaps {
+			t.Errorf("Expected %#v and %#v overlapping to be %v", test.a, test.b, test.overlaps)
+		}
+	}
+}
+
+func TestRolloverSerializes(t *testing.T) {
+	m, _ := NewMinter("x.sd")
+	link := mustTemplate("x.sddk", t)
+	link.CheckDigit = NCDA
+	m.SetRollover(link, mustTemplate("y.sd", t))
+	for i := 0; i < 10; i++ {
+		m.Mint()
+	}
+
+	var buf bytes.Buffer
+	m.WriteJSON(&buf)
+	m2, err := NewMinterFromJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unable to read minter: %s", err)
+	}
+
+	assertEqualS("x.sddk", m2.Template(), "template after reloading", t)
+	if m2.Generations()[1].CheckDigit != NCDA {
+		t.Errorf("Expected the link's check digit algorithm to be kept")
+	}
+	if len(m2.Rollover()) != 1 || m2.Rollover()[0].String() != "y.sd" {
+		t.Errorf("Expected y.sd left in the chain, got %v", m2.Rollover())
+	}
+	assertEqualS(m.Mint(), m2.Mint(), "next noid after reloading", t)
+	if gen, _ := m2.Generation("x.3"); gen != 0 {
+		t.Errorf("Expected x.3 to be generation 0, got %d", gen)
+	}
+
+	bad := `{"Template":"x.sdd","Sequence":0,"Generations":[{"Template":"x.sde"}]}`
+	if _, err = NewMinterFromJSON(bytes.NewBufferString(bad)); err == nil {
+		t.Errorf("Expected an error for generations which collide")
+	}
+}
+
+func TestLeasesFromOldGenerations(t *testing.T) {
+	m, clock := newLeaseMinter(t, "x.sd")
+	m.SetRollover(mustTemplate("y.sd", t))
+	c, _ := m.GrantLease("laptop", 4, clock.now.Add(time.Hour))
+	for m.Template() == "x.sd" {
+		m.Mint()
+	}
+
+	assertEqualS("x.0", c.Mint(), "client minting from the old template", t)
+	c.CloseLease()
+	if err := m.ReturnLease(c); err != nil {
+		t.Fatalf("Unable to return lease: %s", err)
+	}
+	if len(m.Returned()) != 0 {
+		t.Errorf("Expected nothing returned to the new template, got %v", m.Returned())
+	}
+
+	// The lease's unused values were never minted
+	for id, expected := range map[string]bool{"x.0": true, "x.1": false, "x.3": false, "x.4": true} {
+		if minted, _ := m.Minted(id); minted != expected {
+			t.Errorf("Expected %#v minted to be %v", id, expected)
+		}
+	}
+}
+
+func TestRolloverKeepsHolds(t *testing.T) {
+	m, _ := NewMinter("x.sd")
+	m.SetRollover(mustTemplate("y.sd", t))
+	m.Hold("x.3")
+	for m.Template() == "x.sd" {
+		m.Mint()
+	}
+	m.Mint()
+
+	if len(m.Holds()) != 0 {
+		t.Errorf("Expected no holds on the new template, got %v", m.Holds())
+	}
+	for id, expected := range map[string]bool{"x.2": true, "x.3": false, "y.0": true, "y.3": false} {
+		if minted, _ := m.Minted(id); minted != expected {
+			t.Errorf("Expected %#v minted to be %v", id, expected)
+		}
+	}
+
+	var buf bytes.Buffer
+	m.WriteJSON(&buf)
+	m2, err := NewMinterFromJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unable to read minter: %s", err)
+	}
+	if minted, _ := m2.Minted("x.3"); minted {
+		t.Errorf("Expected the old generation's hold to survive serialization")
+	}
+
+	data, _ := m.MarshalBinary()
+	m3 := &Minter{}
+	if err = m3.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unable to unmarshal: %s", err)
+	}
+	if minted, _ := m3.Minted("x.3"); minted {
+		t.Errorf("Expected the old generation's hold to survive binary serialization")
+	}
+}
@@ -0,0 +1,168 @@
+package noid
+
+// This file handles finding noids in free text
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Occurrence is a noid found by a Scanner.  Text is the noid as it appears in
+// the input, starting Offset bytes in, and Canonical is its canonical form.
+type Occurrence struct {
+	Text      string
+	Canonical string
+	Sequence  uint64
+	Offset    int64
+}
+
+// A Scanner reads text a line at a time, finding noids which could have been
+// minted from a template, e.g.:
+//
+//	s := noid.NewScanner(t, r)
+//	for s.Scan() {
+//		fmt.Println(s.Occurrence().Text)
+//	}
+//	if err := s.Err(); err != nil { ... }
+//
+// A noid must stand on its own: it can't have a letter or digit right before
+// or after it.  Each candidate is fully validated, so a check digit weeds out
+// most strings which only look like noids.  Noids can't span lines.
+type Scanner struct {
+	template *Template
+	re       *regexp.Regexp
+	full     *regexp.Regexp
+	reader   *bufio.Reader
+	line     string
+	lineAt   int64
+	pos      int
+	found    []Occurrence
+	current  Occurrence
+	err      error
+}
+
+// NewScanner returns a scanner reading from r.  The template is copied, so
+// changing it afterward has no effect on the scanner.
+func NewScanner(t *Template, r io.Reader) *Scanner {
+	copied := *t
+	return &Scanner{
+		template: &copied,
+		re:       regexp.MustCompile(copied.unanchoredPattern()),
+		full:     copied.Regexp(),
+		reader:   bufio.NewReader(r),
+	}
+}
+
+// Scan advances to the next noid, returning false when the input is used up
+// or there's an error
+func (s *Scanner) Scan() bool {
+	for len(s.found) == 0 {
+		if s.err != nil {
+			return false
+		}
+		s.readLine()
+	}
+
+	s.current, s.found = s.found[0], s.found[1:]
+	return true
+}
+
+// Occurrence returns the noid found by the last call to Scan
+func (s *Scanner) Occurrence() Occurrence {
+	return s.current
+}
+
+// Err returns the first error reading the input, other than io.EOF
+func (s *Scanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+// Reads the next line and finds all its noids
+func (s *Scanner) readLine() {
+	s.lineAt += int64(len(s.line))
+	s.line, s.err = s.reader.ReadString('\n')
+
+	for pos := 0; pos < len(s.line); {
+		loc := s.re.FindStringIndex(s.line[pos:])
+		if loc == nil {
+			break
+		}
+		start, end := pos+loc[0], pos+loc[1]
+
+		if o, ok := s.longestCandidate(start, end); ok {
+			s.found = append(s.found, o)
+			pos = int(o.Offset-s.lineAt) + len(o.Text)
+			continue
+		}
+
+		// Nothing starting here is valid, but a later match may still be
+		_, size := utf8.DecodeRuneInString(s.line[start:])
+		pos = start + size
+	}
+}
+
+// Returns the longest valid noid starting at line[start:], trying shorter
+// matches when the regexp's match at line[start:end] isn't valid, e.g., a
+// grouped noid followed by a separator and a character that looked like its
+// check digit
+func (s *Scanner) longestCandidate(start, end int) (Occurrence, bool) {
+	if o, ok := s.candidate(start, end); ok {
+		return o, true
+	}
+
+	for end--; end > start; end-- {
+		if !utf8.RuneStart(s.line[end]) || !s.full.MatchString(s.line[start:end]) {
+			continue
+		}
+		if o, ok := s.candidate(start, end); ok {
+			return o, true
+		}
+	}
+	return Occurrence{}, false
+}
+
+// Checks the match at line[start:end], returning it if it's a valid noid
+// which stands on its own
+func (s *Scanner) candidate(start, end int) (Occurrence, bool) {
+	// Grouped patterns allow a leading separator, which isn't part of the noid
+	if s.template.GroupSize > 0 {
+		sep := s.template.groupSeparator()
+		for strings.HasPrefix(s.line[start:end], sep) {
+			start += len(sep)
+		}
+	}
+	if start == end {
+		return Occurrence{}, false
+	}
+
+	before, _ := utf8.DecodeLastRuneInString(s.line[:start])
+	after, _ := utf8.DecodeRuneInString(s.line[end:])
+	if start > 0 && isWordRune(before) || end < len(s.line) && isWordRune(after) {
+		return Occurrence{}, false
+	}
+
+	text := s.line[start:end]
+	seq, err := s.template.Decode(text)
+	if err != nil {
+		return Occurrence{}, false
+	}
+	canonical, _ := s.template.Canonical(text)
+
+	return Occurrence{
+		Text:      text,
+		Canonical: canonical,
+		Sequence:  seq,
+		Offset:    s.lineAt + int64(start),
+	}, true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,142 @@
+package noid
+
+import (
+	"strings"
+	"testing"
+)
+
+func scanAll(t *testing.T, template *Template, text string) []Occurrence {
+	s := NewScanner(template, strings.NewReader(text))
+	var found []Occurrence
+	for s.Scan() {
+		found = append(found, s.Occurrence())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("Unexpected error scanning: %s", err)
+	}
+	return found
+}
+
+func TestScanner(t *testing.T) {
+	template, _ := NewTemplate("ph.reeddk")
+	m, _ := NewTemplateMinter(template, 0)
+	a, b, c := m.Mint(), m.Mint(), m.Mint()
+
+	text := "See " + a + " and (" + b + ").\nAlso x" + c + ", " + c + "x, and " + c
+	found := scanAll(t, template, text)
+	if len(found) != 3 {
+		t.Fatalf("Expected 3 noids in %#v, got %#v", text, found)
+	}
+
+	for i, expected := range []string{a, b, c} {
+		assertEqualS(expected, found[i].Text, "found noid", t)
+		assertEqualS(expected, found[i].Canonical, "canonical noid", t)
+		assertEqualUint64(uint64(i), found[i].Sequence, "found noid's sequence", t)
+		assertEqualS(expected, text[found[i].Offset:found[i].Offset+int64(len(expected))], "text at offset", t)
+	}
+}
+
+func TestScannerRejectsBadCheckDigits(t *testing.T) {
+	template, _ := NewTemplate("ph.reeddk")
+	m, _ := NewTemplateMinter(template, 0)
+	id := m.Mint()
+
+	runes := []rune(id)
+	last := len(runes) - 1
+	check := runes[last]
+	for _, char := range ExtendedDigits {
+		if char == check {
+			continue
+		}
+		runes[last] = char
+		if found := scanAll(t, template, "noid "+string(runes)+" here"); len(found) != 0 {
+			t.Errorf("Expected %#v not to be found", string(runes))
+		}
+	}
+}
+
+func TestScannerFindsGroupedARKs(t *testing.T) {
+	template, _ := NewTemplate("ark:/12345/x5reeeeek")
+	template.GroupSize = 4
+	m, _ := NewTemplateMinter(template, 0)
+	id := m.Mint()
+	plain := strings.Replace(id, "-", "", -1)
+
+	found := scanAll(t, template, "<a href=\""+id+"/page2\">-"+plain+"</a>")
+	if len(found) != 2 {
+		t.Fatalf("Expected 2 noids, got %#v", found)
+	}
+	assertEqualS(id, found[0].Text, "grouped ARK", t)
+	assertEqualS(plain, found[1].Text, "ungrouped ARK", t)
+	assertEqualS(id, found[1].Canonical, "canonical ARK", t)
+}
+
+func TestScannerTriesShorterMatches(t *testing.T) {
+	// With single-character groups, a grouped noid followed by "-" and a digit
+	// looks like a longer noid whose check digit is wrong
+	template, _ := NewTemplate("zdk")
+	template.GroupSize = 1
+	m, _ := NewTemplateMinter(template, 0)
+
+	tried := 0
+	for _, id := range m.Peek(64) {
+		if !strings.ContainsRune("01234567", rune(id[len(id)-1])) {
+			continue
+		}
+		for _, digit := range "01234567" {
+			longer := id + "-" + string(digit)
+			if template.Validate(longer) == nil {
+				continue
+			}
+
+			tried++
+			found := scanAll(t, template, "See "+longer+".")
+			if len(found) != 1 || found[0].Text != id {
+				t.Errorf("Expected to find %#v in %#v, got %#v", id, longer, found)
+			}
+		}
+	}
+	if tried == 0 {
+		t.Fatalf("Expected some noids to end with a digit")
+	}
+}
+
+func TestScannerUnlimited(t *testing.T) {
+	template, _ := NewTemplate("zdd")
+	found := scanAll(t, template, "00 17 7777 0123 x12")
+	var texts []string
+	for _, o := range found {
+		texts = append(texts, o.Text)
+	}
+	assertEqualS("00 17 7777", strings.Join(texts, " "), "unlimited noids", t)
+}
+
+func TestMinted(t *testing.T) {
+	m, _ := NewMinter("sddk")
+	a := m.Mint()
+	b := m.Mint()
+	if err := m.Hold(b); err == nil {
+		t.Errorf("Expected holding a minted noid to be an error")
+	}
+	next := m.Peek(2)
+	c, d := next[0], next[1]
+	m.Hold(d)
+	m.Mint()
+
+	for _, tc := range []struct {
+		id     string
+		minted bool
+	}{{a, true}, {b, true}, {c, true}, {d, false}} {
+		minted, err := m.Minted(tc.id)
+		if err != nil {
+			t.Errorf("Unexpected error checking %#v: %s", tc.id, err)
+		}
+		if minted != tc.minted {
+			t.Errorf("Expected minted for %#v to be %v", tc.id, tc.minted)
+		}
+	}
+
+	if _, err := m.Minted("bogus"); err == nil {
+		t.Errorf("Expected an invalid noid to be an error")
+	}
+}
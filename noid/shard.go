@@ -0,0 +1,112 @@
+package noid
+
+// This file handles splitting a template's sequence space between minters
+// which can't coordinate with each other
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Shard identifies one of several minters sharing a template.  Shard ID of
+// Count only mints sequence values where sequence % Count == ID, so each shard
+// works through its own interleaved slice of the template's space and no two
+// shards can mint the same noid.  Interleaving, rather than reserving the
+// high bits for the shard, means every shard draws from the whole range, so
+// random templates stay just as shuffled.
+type Shard struct {
+	ID    uint64
+	Count uint64
+}
+
+// Returns an error if the shard doesn't make sense for a template whose
+// largest sequence value is max
+func (s Shard) validate(max uint64) error {
+	if s.Count == 0 {
+		return errors.New("Shard count must be at least one")
+	}
+	if s.ID >= s.Count {
+		return fmt.Errorf("Shard ID %d must be less than the shard count, %d", s.ID, s.Count)
+	}
+	if max != ^uint64(0) && s.Count > max+1 {
+		return fmt.Errorf("Template only has room for %d shards", max+1)
+	}
+	return nil
+}
+
+// NewShardedMinter returns a minter for one shard of the given template.
+// Every site minting from the template needs the same shard count and a
+// different shard ID.
+func NewShardedMinter(template *Template, id, count uint64) (*Minter, error) {
+	m, err := NewTemplateMinter(template, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	s := Shard{ID: id, Count: count}
+	if err = s.validate(m.generator.maxSequence); err != nil {
+		return nil, err
+	}
+	m.shard = s
+	m.generator.sequenceValue = id
+
+	return m, nil
+}
+
+// Shard returns the minter's shard.  Unsharded minters are shard 0 of 1.
+func (m *Minter) Shard() Shard {
+	if m.shard.Count == 0 {
+		return Shard{ID: 0, Count: 1}
+	}
+	return m.shard
+}
+
+// ShardOf returns the shard which minted the given noid
+func (m *Minter) ShardOf(id string) (uint64, error) {
+	shard, _, err := m.template.DecodeShard(id, m.Shard().Count)
+	return shard, err
+}
+
+// DecodeShard returns which of the given number of shards minted a noid, and
+// the noid's position within that shard's sequence
+func (t *Template) DecodeShard(id string, count uint64) (uint64, uint64, error) {
+	if count == 0 {
+		return 0, 0, errors.New("Shard count must be at least one")
+	}
+
+	seq, err := t.Decode(id)
+	if err != nil {
+		return 0, 0, err
+	}
+	return seq % count, seq / count, nil
+}
+
+// Returns true if the sequence value belongs to the minter's shard
+func (m *Minter) inShard(seq uint64) bool {
+	s := m.Shard()
+	return seq%s.Count == s.ID
+}
+
+// Returns the first value at or after seq which belongs to the minter's
+// shard, or false if there isn't one before max
+func (m *Minter) alignToShard(seq, max uint64) (uint64, bool) {
+	s := m.Shard()
+	offset := (s.ID + s.Count - seq%s.Count) % s.Count
+	if max-seq < offset {
+		return 0, false
+	}
+	return seq + offset, true
+}
+
+// Moves the generator to the shard's next sequence value, returning an error
+// if there isn't one
+func (m *Minter) nextSequence() error {
+	g := m.generator
+	stride := m.Shard().Count
+	if m.maxSequence()-g.sequenceValue < stride {
+		return errors.New("Overflow trying to get next sequence")
+	}
+
+	g.sequenceValue += stride
+	return nil
+}
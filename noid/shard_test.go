@@ -0,0 +1,107 @@
+package noid
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestShardsAreDisjoint(t *testing.T) {
+	template, _ := NewTemplate("x.reddk")
+	seen := make(map[string]uint64)
+
+	for shard := uint64(0); shard < 3; shard++ {
+		m, err := NewShardedMinter(template, shard, 3)
+		if err != nil {
+			t.Fatalf("Unable to create shard %d: %s", shard, err)
+		}
+
+		for !m.Exhausted() {
+			id := m.Mint()
+			if other, ok := seen[id]; ok {
+				t.Fatalf("Shards %d and %d both minted %#v", other, shard, id)
+			}
+			seen[id] = shard
+
+			s, local, err := template.DecodeShard(id, 3)
+			if err != nil {
+				t.Fatalf("Unable to decode %#v: %s", id, err)
+			}
+			assertEqualUint64(shard, s, "shard of "+id, t)
+			owner, _ := m.ShardOf(id)
+			assertEqualUint64(shard, owner, "minter's shard of "+id, t)
+
+			seq, _ := template.Decode(id)
+			assertEqualUint64(seq, local*3+shard, "local sequence of "+id, t)
+		}
+	}
+
+	if len(seen) != 2048 {
+		t.Errorf("Expected the shards to cover all 2048 noids, got %d", len(seen))
+	}
+}
+
+func TestShardsSkipHolds(t *testing.T) {
+	template, _ := NewTemplate("sdd")
+	m, _ := NewShardedMinter(template, 1, 4)
+	m.HoldRange(2, 9)
+
+	assertEqualS("01", m.Mint(), "first noid of shard 1", t)
+	assertEqualS("15", m.Mint(), "noid after the held range", t)
+	assertEqualS("21", m.Mint(), "next noid", t)
+
+	minted, _ := m.Minted("15")
+	if !minted {
+		t.Errorf("Expected 15 to be minted")
+	}
+	minted, _ = m.Minted("00")
+	if minted {
+		t.Errorf("Expected a noid from another shard not to be minted")
+	}
+}
+
+func TestInvalidShards(t *testing.T) {
+	template, _ := NewTemplate("sd")
+	for _, s := range []Shard{{0, 0}, {3, 3}, {0, 9}} {
+		if _, err := NewShardedMinter(template, s.ID, s.Count); err == nil {
+			t.Errorf("Expected shard %#v to be invalid for an 8-noid template", s)
+		}
+	}
+	if _, err := NewShardedMinter(template, 7, 8); err != nil {
+		t.Errorf("Expected 8 shards to fit an 8-noid template: %s", err)
+	}
+}
+
+func TestShardIsPersisted(t *testing.T) {
+	template, _ := NewTemplate("reeee")
+	m, _ := NewShardedMinter(template, 2, 3)
+	m.Mint()
+
+	var buf bytes.Buffer
+	if err := m.WriteJSON(&buf); err != nil {
+		t.Fatalf("Unable to write minter: %s", err)
+	}
+	m2, err := NewMinterFromJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unable to read minter: %s", err)
+	}
+
+	if m2.Shard() != (Shard{2, 3}) {
+		t.Errorf("Expected shard 2 of 3, got %#v", m2.Shard())
+	}
+	for i := 0; i < 100; i++ {
+		assertEqualS(m.Mint(), m2.Mint(), "minting after a round trip", t)
+	}
+}
+
+func TestShardedDateBuckets(t *testing.T) {
+	template, _ := NewTemplate("{YYYY}.sdd")
+	m, _ := NewShardedMinter(template, 1, 2)
+	clock := &fakeClock{time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC)}
+	m.SetClock(clock.Now)
+
+	assertEqualS("2026.01", m.Mint(), "first noid of 2026", t)
+	assertEqualS("2026.03", m.Mint(), "second noid of 2026", t)
+	clock.now = clock.now.AddDate(0, 0, 1)
+	assertEqualS("2027.01", m.Mint(), "first noid of 2027", t)
+}
@@ -0,0 +1,319 @@
+package noid
+
+// This file handles persisting a minter's state to disk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A Store is a minter's state living in a file on disk.  Every change goes
+// through Update, which writes the complete new state to a temporary file,
+// syncs it, and renames it over the old one, then syncs the directory.  A
+// crash at any point leaves either the old state or the new state, never a
+// mix of the two.  Update holds a lock on a sidecar file (the store's
+// filename plus ".lock") from load to save, so separate processes changing
+// the same store take turns rather than minting the same noid.  Creating a
+// store writes the same way, under the same lock.
+//
+// The file is a StoreFile, whose checksum is verified every time it's read,
+// so a hand edit or a flipped bit stops the minter rather than letting it
+// remint old noids.  Custom alphabets the minter uses must be registered
+// before it's loaded, just as when decoding a minter any other way; see
+// Store.Alphabets.
+type Store struct {
+	Filename string
+}
+
+// StoreFormat is the version of the store file format this package writes.
+// Format 1 is the bare minter JSON written before stores were versioned; see
+// Store.Migrate.
+const StoreFormat = 2
+
+// StoreFile is what a store writes to disk: the minter's JSON, as written by
+// WriteJSON, wrapped with the format version, when the store was created and
+// last changed, and a checksum of the minter's JSON exactly as it appears in
+// the file
+type StoreFile struct {
+	Format   int
+	Created  time.Time
+	Modified time.Time
+	Checksum string
+	Minter   json.RawMessage
+}
+
+// ErrUnversionedStore is returned when loading a store written before store
+// files had a format version.  Store.Migrate converts it.
+var ErrUnversionedStore = errors.New("Store file is in the unversioned format and must be migrated")
+
+// Returns the checksum of a minter's JSON as it appears in a store file
+func storeChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func NewStore(filename string) *Store {
+	return &Store{Filename: filename}
+}
+
+func (s *Store) lockFilename() string {
+	return s.Filename + ".lock"
+}
+
+// Create writes a new minter for the given template.  The store's file must
+// not already exist.
+func (s *Store) Create(template string) error {
+	m, err := NewMinter(template)
+	if err != nil {
+		return err
+	}
+
+	return s.CreateFromMinter(m)
+}
+
+// CreateFromMinter writes the given minter as a new store.  The store's file
+// must not already exist.  Like Update, this writes a temporary file and
+// moves it into place while holding the store's lock.
+func (s *Store) CreateFromMinter(m *Minter) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err = os.Lstat(s.Filename); err == nil {
+		return &os.PathError{Op: "create", Path: s.Filename, Err: os.ErrExist}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	now := time.Now()
+	return s.save(m, now, now)
+}
+
+// Alphabets returns the custom alphabets the store's minter uses, keyed by
+// mask character, without registering them.  Callers which trust the file
+// can register these before loading the minter.
+func (s *Store) Alphabets() (map[string]string, error) {
+	data, err := os.ReadFile(s.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	minterJSON := data
+	sf, err := parseStoreFile(data)
+	if err == nil {
+		minterJSON = sf.Minter
+	} else if !errors.Is(err, ErrUnversionedStore) {
+		return nil, fmt.Errorf("%s: %w", s.Filename, err)
+	}
+
+	var sm SerializeableMinter
+	if err = json.Unmarshal(minterJSON, &sm); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.Filename, err)
+	}
+	return sm.Alphabets, nil
+}
+
+// Load reads the minter from the store's file, failing if the file's checksum
+// doesn't match
+func (s *Store) Load() (*Minter, error) {
+	_, m, err := s.load()
+	return m, err
+}
+
+// Verify checks the store's file without changing it, returning its header
+// if the format, checksum, and minter state are all valid
+func (s *Store) Verify() (*StoreFile, error) {
+	sf, _, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	sf.Minter = nil
+	return sf, nil
+}
+
+// Reads and verifies the store's file, returning it along with its minter
+func (s *Store) load() (*StoreFile, *Minter, error) {
+	data, err := os.ReadFile(s.Filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sf, err := parseStoreFile(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", s.Filename, err)
+	}
+
+	var m Minter
+	if err = json.Unmarshal(sf.Minter, &m); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", s.Filename, err)
+	}
+	return sf, &m, nil
+}
+
+// Splits a store file into its header and minter JSON, verifying the format
+// and checksum
+func parseStoreFile(data []byte) (*StoreFile, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if fields["Format"] == nil {
+		if fields["Template"] != nil {
+			return nil, ErrUnversionedStore
+		}
+		return nil, errors.New("Store file has no format version")
+	}
+
+	sf := &StoreFile{}
+	if err := json.Unmarshal(data, sf); err != nil {
+		return nil, err
+	}
+	if sf.Format != StoreFormat {
+		return nil, fmt.Errorf("Store file format %d isn't supported; expected format %d", sf.Format, StoreFormat)
+	}
+	if len(sf.Minter) == 0 {
+		return nil, errors.New("Store file has no minter")
+	}
+	if sum := storeChecksum(sf.Minter); sum != sf.Checksum {
+		return nil, fmt.Errorf("Store file checksum mismatch: the file says %s, but its contents are %s", sf.Checksum, sum)
+	}
+
+	return sf, nil
+}
+
+// Builds a store file for the minter
+func storeFileJSON(m *Minter, created, modified time.Time) ([]byte, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	sf := StoreFile{
+		Format:   StoreFormat,
+		Created:  created.UTC(),
+		Modified: modified.UTC(),
+		Checksum: storeChecksum(data),
+		Minter:   data,
+	}
+	out, err := json.Marshal(sf)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// Migrate converts a store written before store files were versioned to the
+// current format.  The old file's state is validated first, and the store is
+// considered created when the old file was last modified, since nothing
+// earlier is known.  Stores already in the current format are left alone.
+func (s *Store) Migrate() error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := s.Verify(); err == nil {
+		return nil
+	} else if !errors.Is(err, ErrUnversionedStore) {
+		return err
+	}
+
+	info, err := os.Stat(s.Filename)
+	if err != nil {
+		return err
+	}
+	m, err := NewMinterFromJSONFile(s.Filename)
+	if err != nil {
+		return fmt.Errorf("%s: %w", s.Filename, err)
+	}
+
+	return s.save(m, info.ModTime(), time.Now())
+}
+
+// Update loads the minter, hands it to fn, and saves the result if fn
+// returns no error.  If fn fails, the file on disk is left untouched.
+func (s *Store) Update(fn func(*Minter) error) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	sf, m, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if err = fn(m); err != nil {
+		return err
+	}
+
+	return s.save(m, sf.Created, time.Now())
+}
+
+// MintAndBind reserves the next noid and stores its bindings as a single
+// change to the store's file
+func (s *Store) MintAndBind(b Bindings) (string, error) {
+	var id string
+	err := s.Update(func(m *Minter) error {
+		var err error
+		id, err = m.MintAndBind(b)
+		return err
+	})
+
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Writes the minter to a temporary file in the same directory as the store,
+// then moves it into place, syncing the file and then the directory so the
+// rename survives a crash.  The caller must hold the store's lock.
+func (s *Store) save(m *Minter, created, modified time.Time) error {
+	data, err := storeFileJSON(m, created, modified)
+	if err != nil {
+		return err
+	}
+
+	dir, base := filepath.Split(s.Filename)
+	if dir == "" {
+		dir = "."
+	}
+
+	f, err := os.CreateTemp(dir, base+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, 0660)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, s.Filename)
+	}
+
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return syncDir(dir)
+}
@@ -0,0 +1,36 @@
+//go:build !unix
+
+package noid
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// How long lock waits for another process before giving up
+const storeLockTimeout = 30 * time.Second
+
+// Takes the store's lock by creating its lock file, waiting for any other
+// process to finish and remove it.  Without flock, a crashed process can
+// leave the lock file behind, so this gives up eventually rather than
+// hanging forever.
+func (s *Store) lock() (unlock func(), err error) {
+	name := s.lockFilename()
+	deadline := time.Now().Add(storeLockTimeout)
+	for {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0660)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(name) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Timed out waiting for %s; if no other process is using the store, remove it", name)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
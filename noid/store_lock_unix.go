@@ -0,0 +1,34 @@
+//go:build unix
+
+package noid
+
+import (
+	"os"
+	"syscall"
+)
+
+// Takes an exclusive lock on the store's lock file, waiting as long as it
+// takes for any other process to finish.  The lock goes away with the file
+// descriptor, so a crashed process never leaves the store locked.
+func (s *Store) lock() (unlock func(), err error) {
+	f, err := os.OpenFile(s.lockFilename(), os.O_CREATE|os.O_RDWR, 0660)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
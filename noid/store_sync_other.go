@@ -0,0 +1,9 @@
+//go:build !unix
+
+package noid
+
+// Other systems can't open a directory to sync it; their renames are as
+// durable as they're going to get
+func syncDir(dir string) error {
+	return nil
+}
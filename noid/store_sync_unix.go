@@ -0,0 +1,19 @@
+//go:build unix
+
+package noid
+
+import (
+	"os"
+)
+
+// Flushes the directory itself to disk, so a file just renamed into it is
+// still there after a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,259 @@
+package noid
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStoreMintAndBind(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "noid.db"))
+	if err := s.Create("foo.seedee"); err != nil {
+		t.Fatalf("Unable to create store: %s", err)
+	}
+
+	id, err := s.MintAndBind(Bindings{"who": "Jeremy"})
+	if err != nil {
+		t.Fatalf("Unable to mint and bind: %s", err)
+	}
+	assertEqualS("foo.00000", id, "first mint-and-bind", t)
+
+	m, err := s.Load()
+	if err != nil {
+		t.Fatalf("Unable to reload store: %s", err)
+	}
+	assertEqualUint64(1, m.Sequence(), "sequence after one mint", t)
+	assertEqualS("Jeremy", m.Bindings(id)["who"], "binding survives a reload", t)
+	assertEqualS("foo.00001", m.Mint(), "next noid after reload", t)
+}
+
+func TestStoreAlphabets(t *testing.T) {
+	registerTestAlphabet('h', "0123456789abcdef", t)
+	m, _ := NewMinter("x.rhhh")
+	s := NewStore(filepath.Join(t.TempDir(), "noid.db"))
+	if err := s.CreateFromMinter(m); err != nil {
+		t.Fatalf("Unable to create store: %s", err)
+	}
+
+	unregisterAlphabet('h')
+	if _, err := s.Load(); err == nil {
+		t.Errorf("Expected loading a store with an unregistered alphabet to fail")
+	}
+	alphabets, err := s.Alphabets()
+	if err != nil {
+		t.Fatalf("Unable to read store alphabets: %s", err)
+	}
+	assertEqualS("0123456789abcdef", alphabets["h"], "store alphabet", t)
+	assertEqualS("", Alphabet('h'), "alphabet after reading the store", t)
+
+	registerTestAlphabet('h', alphabets["h"], t)
+	if _, err = s.Load(); err != nil {
+		t.Errorf("Unable to load store after registering its alphabet: %s", err)
+	}
+}
+
+func TestStoreConcurrentUpdates(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "noid.db")
+	NewStore(filename).Create("seeee")
+
+	// Each goroutine gets its own Store, as separate processes would
+	const workers, mints = 8, 25
+	ids := make(chan string, workers*mints)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := NewStore(filename)
+			for j := 0; j < mints; j++ {
+				id, err := s.MintAndBind(nil)
+				if err != nil {
+					t.Errorf("Unable to mint: %s", err)
+					return
+				}
+				ids <- id
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("Noid %#v was minted twice", id)
+		}
+		seen[id] = true
+	}
+
+	m, _ := NewStore(filename).Load()
+	assertEqualUint64(workers*mints, m.Sequence(), "sequence after concurrent mints", t)
+}
+
+func TestStoreFailedBindMintsNothing(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "noid.db"))
+	s.Create("foo.seedee")
+
+	_, err := s.MintAndBind(Bindings{"": "nope"})
+	if err == nil {
+		t.Errorf("Expected an empty binding key to be an error")
+	}
+
+	m, _ := s.Load()
+	assertEqualUint64(0, m.Sequence(), "sequence after a failed mint-and-bind", t)
+}
+
+func TestStoreCreateRequiresNewFile(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "noid.db"))
+	s.Create("foo.seedee")
+
+	if err := s.Create("foo.seedee"); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected creating an existing store to fail with os.ErrExist, got %v", err)
+	}
+}
+
+func TestStoreConcurrentCreates(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "noid.db")
+	templates := []string{"a.seedee", "b.seedee", "c.seedee", "d.seedee"}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(templates))
+	for i := range templates {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = NewStore(filename).Create(templates[i])
+		}(i)
+	}
+	wg.Wait()
+
+	var winner string
+	for i, err := range errs {
+		if err == nil {
+			if winner != "" {
+				t.Errorf("Expected only one create to succeed, but %#v and %#v both did", winner, templates[i])
+			}
+			winner = templates[i]
+		} else if !errors.Is(err, os.ErrExist) {
+			t.Errorf("Unexpected error creating store: %s", err)
+		}
+	}
+
+	m, err := NewStore(filename).Load()
+	if err != nil {
+		t.Fatalf("Unable to load store: %s", err)
+	}
+	assertEqualS(winner, m.Template(), "template of the store that won", t)
+
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if e.Name() != "noid.db" && e.Name() != "noid.db.lock" {
+			t.Errorf("Unexpected file %#v left behind", e.Name())
+		}
+	}
+}
+
+func TestStoreChecksum(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "noid.db"))
+	s.Create("foo.seedee")
+	s.MintAndBind(nil)
+
+	sf, err := s.Verify()
+	if err != nil {
+		t.Fatalf("Unable to verify a fresh store: %s", err)
+	}
+	if sf.Format != StoreFormat {
+		t.Errorf("Expected format %d, got %d", StoreFormat, sf.Format)
+	}
+
+	// Hand-editing the sequence must be caught, not quietly remint noids
+	data, _ := os.ReadFile(s.Filename)
+	tampered := bytes.Replace(data, []byte(`"Sequence":1`), []byte(`"Sequence":0`), 1)
+	if bytes.Equal(data, tampered) {
+		t.Fatalf("Test setup failed: no sequence found in %s", data)
+	}
+	os.WriteFile(s.Filename, tampered, 0660)
+
+	if _, err = s.Load(); err == nil {
+		t.Errorf("Expected an error loading a tampered store")
+	}
+	if _, err = s.Verify(); err == nil {
+		t.Errorf("Expected an error verifying a tampered store")
+	}
+	if _, err = s.MintAndBind(nil); err == nil {
+		t.Errorf("Expected an error minting from a tampered store")
+	}
+}
+
+func TestStoreTimestamps(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "noid.db"))
+	s.Create("foo.seedee")
+	before, _ := s.Verify()
+	if !before.Created.Equal(before.Modified) {
+		t.Errorf("Expected a new store's created and modified times to match")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	s.MintAndBind(nil)
+	after, _ := s.Verify()
+	if !after.Created.Equal(before.Created) {
+		t.Errorf("Expected created time %s to survive an update, got %s", before.Created, after.Created)
+	}
+	if !after.Modified.After(before.Modified) {
+		t.Errorf("Expected modified time to move past %s, got %s", before.Modified, after.Modified)
+	}
+}
+
+func TestStoreMigrate(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "noid.db"))
+	m, _ := NewMinter("foo.seedee")
+	m.Mint()
+	f, _ := os.Create(s.Filename)
+	m.WriteJSON(f)
+	f.Close()
+
+	if _, err := s.Load(); !errors.Is(err, ErrUnversionedStore) {
+		t.Fatalf("Expected ErrUnversionedStore loading an old store, got %v", err)
+	}
+
+	if err := s.Migrate(); err != nil {
+		t.Fatalf("Unable to migrate: %s", err)
+	}
+	sf, err := s.Verify()
+	if err != nil {
+		t.Fatalf("Unable to verify a migrated store: %s", err)
+	}
+	if sf.Created.After(sf.Modified) {
+		t.Errorf("Expected created time %s to be no later than modified time %s", sf.Created, sf.Modified)
+	}
+
+	m, _ = s.Load()
+	assertEqualS("foo.00001", m.Mint(), "next noid after migration", t)
+
+	// Migrating again changes nothing
+	data, _ := os.ReadFile(s.Filename)
+	if err = s.Migrate(); err != nil {
+		t.Fatalf("Unable to migrate a current store: %s", err)
+	}
+	again, _ := os.ReadFile(s.Filename)
+	if !bytes.Equal(data, again) {
+		t.Errorf("Expected migrating a current store to leave it alone")
+	}
+}
+
+func TestStoreRejectsUnknownFormat(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "noid.db"))
+	os.WriteFile(s.Filename, []byte(`{"Format":99,"Minter":{}}`), 0660)
+
+	if _, err := s.Load(); err == nil {
+		t.Errorf("Expected an error loading an unknown format")
+	}
+	if err := s.Migrate(); err == nil {
+		t.Errorf("Expected an error migrating an unknown format")
+	}
+}
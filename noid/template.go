@@ -2,6 +2,7 @@ package noid
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 )
 
@@ -13,22 +14,74 @@ const (
 	SequentialUnlimited
 )
 
+// Characters which, at the end of a template's prefix, NewTemplate treats as
+// the separator between the prefix and the minted suffix.  Any other
+// separator has to be set with SetSeparator.
+const templateSeparators = "./:-_"
+
+// Template describes the noids a minter creates.  Templates starting with
+// "ark:/" mint ARKs, in which case ARK is set and takes the place of Prefix
+// and Separator.
+//
+// CheckDigit is the algorithm used when HasCheckDigit is true.  It isn't part
+// of the template string; if it's nil, LegacyCheckDigit is used.
+//
+// If GroupSize is set, minted noids have everything after the prefix (or for
+// ARKs, after the NAAN) split into groups of that size, joined by
+// GroupSeparator, or DefaultGroupSeparator if that's empty.  Groups are
+// counted from the left unless GroupFromRight is set.  Separators are ignored
+// when validating and decoding, and never count toward a check digit.  Like
+// CheckDigit, these settings aren't part of the template string.
 type Template struct {
 	Prefix         string
+	Separator      string
+	ARK            *ARK
 	Ordering       Ordering
 	Mask           string
 	HasCheckDigit  bool
-	templateString string
+	CheckDigit     CheckDigit
+	GroupSize      int
+	GroupSeparator string
+	GroupFromRight bool
 }
 
+// NewTemplate parses a template string.  The suffix template is the ordering
+// character, mask, and optional check digit character at the end of the
+// string; everything before that is the prefix, which may contain dots,
+// slashes, colons, or anything else.  If the prefix ends in one of ".", "/",
+// ":", "-", or "_", that character is the separator.  Otherwise there's no
+// separator at all, and any other punctuation stays in the prefix, e.g.:
+//
+//	"reedeek"        -> no prefix
+//	"a.b.reedeek"    -> prefix "a.b", separator "."
+//	"lib/ms-reedeek" -> prefix "lib/ms", separator "-"
+//	"x5reedeek"      -> prefix "x5", no separator
+//	"foo+reedeek"    -> prefix "foo+", no separator
+//
+// Either way, noids are minted and validated with the prefix and separator
+// run together, so the split only matters to code reading Prefix and
+// Separator.  SetSeparator picks a different split, such as "+" for
+// "foo+reedeek", or no separator at all.
 func NewTemplate(template string) (*Template, error) {
-	var suffix string
-	var err error
+	head, suffix, err := splitTemplateString(template)
+	if err != nil {
+		return nil, err
+	}
 
 	// You know what's hip and cool these days?  Storing values immediately on
 	// instantiation when said values are essentially static, read-only data
-	t := &Template{templateString: template}
-	t.Prefix, suffix = splitTemplateString(template)
+	t := &Template{}
+	if strings.HasPrefix(head, arkLabel) {
+		t.ARK, err = parseARKPrefix(head)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		t.Prefix, t.Separator = splitPrefix(head)
+		if _, err = splitDatePrefix(t.Prefix); err != nil {
+			return nil, err
+		}
+	}
 	t.HasCheckDigit, suffix = getCheckDigitFromSuffix(suffix)
 	t.Ordering, err = getOrderingFromChar(suffix[0])
 
@@ -37,25 +90,215 @@ func NewTemplate(template string) (*Template, error) {
 	}
 
 	t.Mask = suffix[1:]
+	if t.Mask == "" {
+		return nil, errors.New("Template mask must have at least one character")
+	}
 
 	return t, nil
 }
 
-// Returns the original string used to construct this template
+// SetSeparator splits the template's prefix so that it ends with the given
+// separator, which may be any string, including "" for no separator at all,
+// e.g., "+" on "foo+reedeek" gives prefix "foo" and separator "+".  The
+// prefix must end with the separator, and ARK templates can't change theirs.
+// The noids the template mints don't change.  Minters persist the separator
+// along with the template.
+func (t *Template) SetSeparator(separator string) error {
+	if t.ARK != nil {
+		return errors.New("ARK templates can't have a custom separator")
+	}
+
+	head := t.Prefix + t.Separator
+	if !strings.HasSuffix(head, separator) {
+		return fmt.Errorf("Template prefix %#v doesn't end with separator %#v", head, separator)
+	}
+	prefix := head[:len(head)-len(separator)]
+	if _, err := splitDatePrefix(prefix); err != nil {
+		return err
+	}
+
+	t.Prefix, t.Separator = prefix, separator
+	return nil
+}
+
+// Returns true if the template's separator isn't the one NewTemplate would
+// find in its string
+func (t *Template) customSeparator() bool {
+	if t.ARK != nil {
+		return false
+	}
+	_, sep := splitPrefix(t.Prefix + t.Separator)
+	return sep != t.Separator
+}
+
+// Returns the template string, which NewTemplate can parse back into an
+// identical template, aside from a separator set with SetSeparator
 func (t Template) String() string {
-	return t.templateString
+	head := t.Prefix + t.Separator
+	if t.ARK != nil {
+		head = t.ARK.String()
+	}
+
+	s := head + string(t.Ordering.char()) + t.Mask
+	if t.HasCheckDigit {
+		s += "k"
+	}
+	return s
+}
+
+// Returns the number of bits the template's mask holds, or an error if the
+// mask uses a character with no registered alphabet
+func (t *Template) maskBits() (int, error) {
+	total := 0
+	for _, char := range t.Mask {
+		mc := lookupMaskCharacter(char)
+		if mc == nil {
+			return 0, fmt.Errorf("Mask character %#v has no alphabet", string(char))
+		}
+		total += int(mc.bits)
+	}
+
+	return total, nil
+}
+
+// Returns the check digit algorithm for the template, or nil if the template
+// has no check digit
+func (t *Template) checkDigit() CheckDigit {
+	if !t.HasCheckDigit {
+		return nil
+	}
+	if t.CheckDigit == nil {
+		return LegacyCheckDigit
+	}
+	return t.CheckDigit
+}
+
+// Builds a full noid from a generated suffix by adding the prefix and, if the
+// template calls for it, the check digit
+func (t *Template) format(suffix string) string {
+	var head, result string
+	if t.ARK != nil {
+		head = arkLabel + t.ARK.NAAN + "/"
+		result = t.ARK.format(suffix, t.checkDigit())
+	} else {
+		head = t.Prefix + t.Separator
+		result = head + suffix
+		if cd := t.checkDigit(); cd != nil {
+			result = result + string(cd.Compute(result))
+		}
+	}
+
+	return head + t.group(result[len(head):])
+}
+
+// Returns the suffix part of a noid after verifying its prefix and check
+// digit.  The suffix's characters aren't examined.
+func (t *Template) suffixOf(id string) (string, error) {
+	if t.ARK != nil {
+		return t.ARK.suffixOf(id, t.checkDigit())
+	}
+
+	p := t.Prefix + t.Separator
+	if !strings.HasPrefix(id, p) {
+		return "", fmt.Errorf("%#v doesn't start with %#v", id, p)
+	}
+	suffix := t.ungroup(id[len(p):])
+
+	if cd := t.checkDigit(); cd != nil {
+		runes := []rune(suffix)
+		if len(runes) < 2 {
+			return "", fmt.Errorf("%#v is too short to have a check digit", id)
+		}
+
+		last := len(runes) - 1
+		suffix = string(runes[:last])
+		if cd.Compute(p+suffix) != runes[last] {
+			return "", fmt.Errorf("%#v has an incorrect check digit", id)
+		}
+	}
+
+	return suffix, nil
 }
 
-// Returns the prefix and suffix parts of a template string, defaulting to a
-// prefix of "" when no period is in the string
-func splitTemplateString(s string) (string, string) {
-	parts := strings.Split(s, ".")
+// Validate returns an error if the given noid could not have been minted from
+// this template
+func (t *Template) Validate(id string) error {
+	t, err := t.resolve(id)
+	if err != nil {
+		return err
+	}
 
-	if len(parts) == 1 {
-		return "", s
+	suffix, err := t.suffixOf(id)
+	if err != nil {
+		return err
 	}
 
-	return parts[0], parts[1]
+	_, err = NewSuffixGenerator(t, 0).valueOf(suffix)
+	return err
+}
+
+// Decode returns the sequence value which mints the given noid, undoing the
+// shuffling done for random templates
+func (t *Template) Decode(id string) (uint64, error) {
+	t, err := t.resolve(id)
+	if err != nil {
+		return 0, err
+	}
+
+	suffix, err := t.suffixOf(id)
+	if err != nil {
+		return 0, err
+	}
+
+	return NewSuffixGenerator(t, 0).sequenceOf(suffix)
+}
+
+// Canonical returns the given noid exactly as it would have been minted,
+// e.g., with any ARK normalization applied and qualifiers removed
+func (t *Template) Canonical(id string) (string, error) {
+	t, err := t.resolve(id)
+	if err != nil {
+		return "", err
+	}
+
+	seq, err := t.Decode(id)
+	if err != nil {
+		return "", err
+	}
+
+	return t.format(NewSuffixGenerator(t, seq).ToString()), nil
+}
+
+// Splits a template string into its head (prefix and separator) and the
+// suffix template, which is the ordering character, mask, and optional check
+// digit character.  Mask characters are never ordering characters, so
+// scanning back from the end past the check digit and mask always finds the
+// ordering character, no matter what the prefix contains.
+func splitTemplateString(s string) (string, string, error) {
+	end := len(s)
+	if strings.HasSuffix(s, "k") {
+		end--
+	}
+
+	i := end
+	for i > 0 && isMaskCharacter(rune(s[i-1])) {
+		i--
+	}
+	if i == 0 {
+		return "", "", errors.New("Template must have an ordering character: 'r', 's', or 'z'")
+	}
+
+	return s[:i-1], s[i-1:], nil
+}
+
+// Splits the head of a template string into prefix and separator
+func splitPrefix(head string) (string, string) {
+	last := len(head) - 1
+	if last >= 0 && strings.IndexByte(templateSeparators, head[last]) != -1 {
+		return head[:last], head[last:]
+	}
+
+	return head, ""
 }
 
 // Returns whether or not the final character is a check digit ("k") as well as
@@ -70,6 +313,17 @@ func getCheckDigitFromSuffix(suffix string) (bool, string) {
 	return false, suffix
 }
 
+// Returns the template character for an ordering
+func (o Ordering) char() byte {
+	switch o {
+	case SequentialLimited:
+		return 's'
+	case SequentialUnlimited:
+		return 'z'
+	}
+	return 'r'
+}
+
 func getOrderingFromChar(c byte) (Ordering, error) {
 	var err error
 	var order Ordering
@@ -1,6 +1,9 @@
 package noid
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+)
 
 func assertTemplateAttributeS(templateString, attribute, expected, actual string, t *testing.T) {
 	if expected != actual {
@@ -70,3 +73,180 @@ func TestMoreMoarMOAR(t *testing.T) {
 	assertTemplateAttributeO(str, "ordering", SequentialUnlimited, template.Ordering, t)
 	assertTemplateAttributeB(str, "hasCheckDigit", false, template.HasCheckDigit, t)
 }
+
+func TestValidate(t *testing.T) {
+	var valid = map[string][]string{
+		"foo.seedeek": {"foo.00000f", "foo.000z9r"},
+		"bar.seedeek": {"bar.000004", "bar.000z9d"},
+		"reedee":      {"q67j4", "tt0fv", "9t0fv"},
+		"zdd":         {"00", "77", "100", "7777777"},
+	}
+	var invalid = map[string][]string{
+		"foo.seedeek": {"foo.00000g", "bar.00000f", "foo.0000f", "foo.00800x", "f", ""},
+		"reedee":      {"q67j", "q67j4g", "q68j4", "Q67J4"},
+		"zdd":         {"0", "010", "8", "7a"},
+	}
+
+	for str, ids := range valid {
+		template, _ := NewTemplate(str)
+		for _, id := range ids {
+			if err := template.Validate(id); err != nil {
+				t.Errorf("Expected %#v to be valid for %#v, but got %s", id, str, err)
+			}
+		}
+	}
+
+	for str, ids := range invalid {
+		template, _ := NewTemplate(str)
+		for _, id := range ids {
+			if err := template.Validate(id); err == nil {
+				t.Errorf("Expected %#v to be invalid for %#v", id, str)
+			}
+		}
+	}
+}
+
+func TestValidateAcceptsMintedNoids(t *testing.T) {
+	for _, str := range []string{"foo.reedeek", "seek", "zek", "x.sdd", "foo+reedeek"} {
+		template, _ := NewTemplate(str)
+		minter, _ := NewMinter(str)
+		for i := 0; i < 2000 && !minter.Exhausted(); i++ {
+			id := minter.Mint()
+			if err := template.Validate(id); err != nil {
+				t.Fatalf("Minted %#v from %#v, but it didn't validate: %s", id, str, err)
+			}
+		}
+	}
+}
+
+func TestGeneralizedPrefixes(t *testing.T) {
+	var tests = []struct {
+		template, prefix, separator, mask string
+	}{
+		{"a.b.reee", "a.b", ".", "eee"},
+		{"lib.ms.reedeek", "lib.ms", ".", "eedee"},
+		{"http://n2t.net/zdd", "http://n2t.net", "/", "dd"},
+		{"urn:foo:seedee", "urn:foo", ":", "eedee"},
+		{"foo-reedee", "foo", "-", "eedee"},
+		{"x5reedeek", "x5", "", "eedee"},
+		{"barseedee", "bar", "", "eedee"},
+		{".zdd", "", ".", "dd"},
+
+		// NewTemplate only finds "./:-_" separators on its own; anything else
+		// stays in the prefix unless SetSeparator says otherwise
+		{"foo+reedee", "foo+", "", "eedee"},
+		{"a.b~zdd", "a.b~", "", "dd"},
+	}
+
+	for _, test := range tests {
+		template, err := NewTemplate(test.template)
+		if err != nil {
+			t.Errorf("Unable to parse %#v: %s", test.template, err)
+			continue
+		}
+
+		assertTemplateAttributeS(test.template, "prefix", test.prefix, template.Prefix, t)
+		assertTemplateAttributeS(test.template, "separator", test.separator, template.Separator, t)
+		assertTemplateAttributeS(test.template, "mask", test.mask, template.Mask, t)
+		assertTemplateAttributeS(test.template, "string", test.template, template.String(), t)
+	}
+}
+
+func TestCustomSeparators(t *testing.T) {
+	var tests = []struct {
+		template, separator, prefix string
+	}{
+		{"foo+reedee", "+", "foo"},
+		{"a.b~zdd", "~", "a.b"},
+		{"lib::zdd", "::", "lib"},
+		{"lib.ms.reedeek", "", "lib.ms."},
+		{"x5reedeek", "5", "x"},
+	}
+
+	for _, test := range tests {
+		template := mustTemplate(test.template, t)
+		if err := template.SetSeparator(test.separator); err != nil {
+			t.Errorf("Unable to set separator %#v on %#v: %s", test.separator, test.template, err)
+			continue
+		}
+
+		assertTemplateAttributeS(test.template, "prefix", test.prefix, template.Prefix, t)
+		assertTemplateAttributeS(test.template, "separator", test.separator, template.Separator, t)
+		assertTemplateAttributeS(test.template, "string", test.template, template.String(), t)
+	}
+
+	// The separator never changes what's minted
+	plain, _ := NewMinter("foo+reedeek")
+	custom := mustTemplate("foo+reedeek", t)
+	custom.SetSeparator("+")
+	m, _ := NewTemplateMinter(custom, 0)
+	for i := 0; i < 10; i++ {
+		assertEqualS(plain.Mint(), m.Mint(), "minting with a custom separator", t)
+	}
+
+	bad := mustTemplate("foo+reedee", t)
+	for _, sep := range []string{"-", "o+r"} {
+		if err := bad.SetSeparator(sep); err == nil {
+			t.Errorf("Expected separator %#v to be rejected for %#v", sep, bad.String())
+		}
+	}
+	ark := mustTemplate("ark:/12345/x5reedeek", t)
+	if err := ark.SetSeparator(""); err == nil {
+		t.Errorf("Expected ARK templates to reject custom separators")
+	}
+}
+
+func TestCustomSeparatorsArePersisted(t *testing.T) {
+	template := mustTemplate("foo+reedeek", t)
+	template.SetSeparator("+")
+	none := mustTemplate("lib.reedee", t)
+	none.SetSeparator("")
+
+	m, _ := NewTemplateMinter(template, 0)
+	m.SetRollover(none)
+	m.Mint()
+
+	var buf bytes.Buffer
+	if err := m.WriteJSON(&buf); err != nil {
+		t.Fatalf("Unable to write minter: %s", err)
+	}
+	fromJSON, err := NewMinterFromJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unable to read minter: %s", err)
+	}
+	data, _ := m.MarshalBinary()
+	fromBinary := &Minter{}
+	if err = fromBinary.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unable to unmarshal minter: %s", err)
+	}
+
+	for _, restored := range []*Minter{fromJSON, fromBinary} {
+		assertEqualS("foo", restored.template.Prefix, "restored prefix", t)
+		assertEqualS("+", restored.template.Separator, "restored separator", t)
+		assertEqualS("lib.", restored.rollover[0].Prefix, "restored rollover prefix", t)
+		assertEqualS("", restored.rollover[0].Separator, "restored rollover separator", t)
+	}
+
+	text, _ := template.MarshalText()
+	assertEqualS("foo+reedeek?separator=%2B", string(text), "template text", t)
+	var parsed Template
+	if err = parsed.UnmarshalText(text); err != nil {
+		t.Fatalf("Unable to unmarshal %#v: %s", string(text), err)
+	}
+	assertEqualS("+", parsed.Separator, "separator from text", t)
+
+	text, _ = none.MarshalText()
+	assertEqualS("lib.reedee?separator=", string(text), "template text with no separator", t)
+	if err = parsed.UnmarshalText(text); err != nil {
+		t.Fatalf("Unable to unmarshal %#v: %s", string(text), err)
+	}
+	assertEqualS("lib.", parsed.Prefix, "prefix from text with no separator", t)
+}
+
+func TestInvalidTemplateStrings(t *testing.T) {
+	for _, str := range []string{"", "k", "eedee", "foo.bar", "foo.rk", "foo.xeedee", "a.b.c"} {
+		if _, err := NewTemplate(str); err == nil {
+			t.Errorf("Expected %#v to be invalid", str)
+		}
+	}
+}